@@ -0,0 +1,35 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"flag"
+	"fmt"
+)
+
+func runChecks(args []string) error {
+
+	fs := flag.NewFlagSet("checks", flag.ExitOnError)
+
+	var inputDir string
+	inputDirFlag(fs, &inputDir)
+
+	var outputCSV string
+	fs.StringVar(&outputCSV, "output", "", "path of the check report csv to write (default: 02_check_reports-<time>.csv in the inputdir)")
+
+	fs.Parse(args)
+
+	if err := checkInputDir(inputDir); err != nil {
+		return err
+	}
+	if outputCSV == "" {
+		outputCSV = fmt.Sprintf("%s/02_check_reports-%s.csv", inputDir, reportTime())
+	}
+
+	results, err := pdf.ReadCheckReportsInDirectory(inputDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Writing check reports for %d scripts to %s\n", len(results), outputCSV)
+
+	return pdf.WriteResultsToCSV(results, outputCSV)
+}
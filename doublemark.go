@@ -0,0 +1,69 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"errors"
+	"fmt"
+	"flag"
+	"path/filepath"
+)
+
+func runDoubleMark(args []string) error {
+
+	fs := flag.NewFlagSet("doublemark", flag.ExitOnError)
+
+	var rawCSV string
+	fs.StringVar(&rawCSV, "raw", "", "path to an existing raw form values csv (01_raw_form_values-*.csv)")
+
+	var partsCSV string
+	partsFlag(fs, &partsCSV)
+
+	var opts pdf.DoubleMarkingOptions
+	fs.Float64Var(&opts.PartTolerance, "tolerance", 1, "largest difference between the two markers on a part that can be agreed without a third marker")
+	fs.Float64Var(&opts.TotalTolerance, "total-tolerance", 5, "largest difference between the two markers' totals that can be agreed without a third marker")
+
+	var agree string
+	fs.StringVar(&agree, "agree", pdf.AgreeMean, "how to propose the agreed mark when the markers are within tolerance: mean or higher")
+
+	var outputDir string
+	fs.StringVar(&outputDir, "outputdir", "", "folder to write the double marking report to (default: the folder containing the raw csv)")
+
+	fs.Parse(args)
+
+	var err error
+	if opts.Agree, err = pdf.ParseAgree(agree); err != nil {
+		return err
+	}
+
+	if rawCSV == "" {
+		return errors.New("specify the raw form values csv with -raw")
+	}
+	if outputDir == "" {
+		outputDir = filepath.Dir(rawCSV)
+	}
+
+	parts, err := loadParts(outputDir, partsCSV)
+	if err != nil {
+		return err
+	}
+
+	form_values, err := pdf.ReadFormValuesCSV(rawCSV)
+	if err != nil {
+		return err
+	}
+	if err := checkSingleCourse(form_values); err != nil {
+		return err
+	}
+
+	rows := pdf.ReconcileDoubleMarking(form_values, parts, opts)
+
+	disagreements := 0
+	for _, row := range rows {
+		if row.Part == "Total" && row.Status == pdf.DoubleDisagree {
+			disagreements++
+		}
+	}
+	fmt.Printf("%d scripts need a third marker\n", disagreements)
+
+	return pdf.WriteDoubleMarkingCSV(rows, fmt.Sprintf("%s/04_double_marking-%s.csv", outputDir, reportTime()))
+}
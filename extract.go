@@ -0,0 +1,79 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"flag"
+	"fmt"
+	"path/filepath"
+)
+
+func runExtract(args []string) error {
+
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+
+	var inputDir string
+	inputDirFlag(fs, &inputDir)
+
+	var workers int
+	jobsFlag(fs, &workers)
+
+	var configJSON string
+	configFlag(fs, &configJSON)
+
+	var noCache bool
+	cacheFlag(fs, &noCache)
+
+	var outputCSV string
+	fs.StringVar(&outputCSV, "output", "", "path of the raw form values csv to write (default: 01_raw_form_values-<time>.csv in the inputdir)")
+
+	fs.Parse(args)
+
+	if err := checkInputDir(inputDir); err != nil {
+		return err
+	}
+	conv, err := loadConventions(configJSON)
+	if err != nil {
+		return err
+	}
+	report_time := reportTime()
+	if outputCSV == "" {
+		outputCSV = fmt.Sprintf("%s/01_raw_form_values-%s.csv", inputDir, report_time)
+	}
+
+	// Look at all PDFs in inputDir (including subdirectories)
+	fmt.Println("Looking at input directory: ",inputDir)
+
+	// Read the raw form values, and save them as a csv
+	_, script_errors, changes := pdf.ReadFormsInDirectory(inputDir, outputCSV, pdf.ExtractOptions{Workers: workers, Conventions: conv, CachePath: cachePath(filepath.Dir(outputCSV), noCache)})
+	printChanges(changes)
+	fmt.Println("Raw form values written to", outputCSV)
+
+	return writeScriptErrors(script_errors, filepath.Dir(outputCSV), report_time)
+}
+
+// writeScriptErrors saves the problems with individual scripts next to the other outputs, if there are any
+func writeScriptErrors(script_errors []pdf.ScriptError, outputDir string, report_time string) error {
+	if len(script_errors) == 0 {
+		return nil
+	}
+	csv_path := fmt.Sprintf("%s/03_script_errors-%s.csv", outputDir, report_time)
+	json_path := fmt.Sprintf("%s/03_script_errors-%s.json", outputDir, report_time)
+	fmt.Printf("%d problems with scripts - see %s\n", len(script_errors), csv_path)
+	return pdf.WriteScriptErrors(script_errors, csv_path, json_path)
+}
+
+// extractForms reads the form values from the PDFs in inputDir, saving the raw values and any problems with the scripts
+// (and the cache) in outputDir
+func extractForms(inputDir string, outputDir string, workers int, configJSON string, noCache bool, report_time string) ([]pdf.FormValues, error) {
+	if err := checkInputDir(inputDir); err != nil {
+		return nil, err
+	}
+	conv, err := loadConventions(configJSON)
+	if err != nil {
+		return nil, err
+	}
+	csv_path := fmt.Sprintf("%s/01_raw_form_values-%s.csv", outputDir, report_time)
+	form_values, script_errors, changes := pdf.ReadFormsInDirectory(inputDir, csv_path, pdf.ExtractOptions{Workers: workers, Conventions: conv, CachePath: cachePath(outputDir, noCache)})
+	printChanges(changes)
+	return form_values, writeScriptErrors(script_errors, outputDir, report_time)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func runFeedback(args []string) error {
+
+	fs := flag.NewFlagSet("feedback", flag.ExitOnError)
+
+	var inputDir string
+	inputDirFlag(fs, &inputDir)
+
+	var workers int
+	jobsFlag(fs, &workers)
+
+	var configJSON string
+	configFlag(fs, &configJSON)
+
+	var noCache bool
+	cacheFlag(fs, &noCache)
+
+	var partsCSV string
+	partsFlag(fs, &partsCSV)
+
+	var rounding string
+	roundingFlag(fs, &rounding)
+
+	var gradesJSON string
+	gradesFlag(fs, &gradesJSON)
+
+	var scalingJSON string
+	scalingFlag(fs, &scalingJSON)
+
+	var rawCSV string
+	fs.StringVar(&rawCSV, "raw", "", "path to an existing raw form values csv to use instead of reading the PDFs again")
+
+	var comments bool
+	fs.BoolVar(&comments, "comments", false, "include the markers' comments")
+
+	var ingestCSV string
+	fs.StringVar(&ingestCSV, "ingest", "", "path to the Learn ingest report, to name each pack by matriculation number instead of exam number")
+
+	var outputDir string
+	fs.StringVar(&outputDir, "outputdir", "", "folder to save the feedback packs in (default: next to the inputdir, with -feedback on the end of its name)")
+
+	fs.Parse(args)
+
+	var opts pdf.ValidationOptions
+	var err error
+	if opts.TotalRounding, err = pdf.ParseRounding(rounding); err != nil {
+		return err
+	}
+	if opts.Grades, err = loadGrades(gradesJSON); err != nil {
+		return err
+	}
+	conv, err := loadConventions(configJSON)
+	if err != nil {
+		return err
+	}
+
+	matric := map[string]string{}
+	if ingestCSV != "" {
+		if matric, err = pdf.MatriculationNumbers(ingestCSV); err != nil {
+			return err
+		}
+	}
+
+	// keep the packs out of the inputdir, so they are not read as scripts next time
+	if outputDir == "" {
+		abs, err := filepath.Abs(inputDir)
+		if err != nil {
+			return err
+		}
+		outputDir = abs + "-feedback"
+	}
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	report_time := reportTime()
+	partsDir := inputDir
+	var form_values []pdf.FormValues
+	if rawCSV != "" {
+		partsDir = filepath.Dir(rawCSV)
+		form_values, err = pdf.ReadFormValuesCSV(rawCSV)
+	} else {
+		form_values, err = extractForms(inputDir, inputDir, workers, configJSON, noCache, report_time)
+	}
+	if err != nil {
+		return err
+	}
+
+	parts, err := loadParts(partsDir, partsCSV)
+	if err != nil {
+		return err
+	}
+	if opts.Scaling, err = loadScaling(scalingJSON, parts); err != nil {
+		return err
+	}
+	if err := checkSingleCourse(form_values); err != nil {
+		return err
+	}
+	summary := pdf.SummariseMarking(form_values, parts, opts)
+
+	// only scripts that are completely marked, with no problems, are ready to go back to students
+	paths := pdf.ScriptPaths(inputDir, conv)
+	written := 0
+	script_errors := []pdf.ScriptError{}
+	for _, script := range summary.Scripts {
+		if script.Status != pdf.StatusComplete {
+			fmt.Printf(" - skipping %s, which is %s\n", script.ExamNumber, script.Status)
+			continue
+		}
+		name := script.ExamNumber
+		if ingestCSV != "" {
+			if matric[script.ExamNumber] == "" {
+				fmt.Printf(" - no matriculation number for %s in the ingest report, so naming it by exam number\n", script.ExamNumber)
+			} else {
+				name = matric[script.ExamNumber]
+			}
+		}
+		output_path := filepath.Join(outputDir, name+".pdf")
+		if err := pdf.WriteFeedback(paths[script.ExamNumber], summary, script, pdf.FeedbackOptions{Comments: comments, Conventions: conv}, output_path); err != nil {
+			fmt.Println(" - ", script.ExamNumber, err)
+			script_errors = append(script_errors, pdf.ScriptError{File: output_path, Stage: pdf.StageOutput, Reason: err.Error()})
+			os.Remove(output_path)
+			continue
+		}
+		written++
+	}
+	fmt.Printf("Saved %d feedback packs in %s\n", written, outputDir)
+
+	if len(script_errors) > 0 {
+		return writeScriptErrors(script_errors, outputDir, report_time)
+	}
+	return nil
+}
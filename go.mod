@@ -0,0 +1,5 @@
+module github.com/georgekinnear/gradex-extract
+
+go 1.16
+
+require github.com/gocarina/gocsv v0.0.0-20201208093247-67c824bc04d4
@@ -1,8 +1,8 @@
 /*
- * Get form field data for a specific field from a PDF file.
+ * Get the marks out of a set of gradex-enabled PDF scripts.
  *
- * Run as: go run pdf_form_get_field_data <input.pdf> [full field name]
- * If no field specified will output values for all fields.
+ * Run as: gradex-extract <command> [flags]
+ * Use "gradex-extract <command> -h" to see the flags for each command.
  */
 
 package main
@@ -13,63 +13,194 @@ import (
 	"time"
 	"os"
 	"fmt"
+	"runtime"
+	"path/filepath"
 )
 
+// each command parses its own flags from the arguments that follow the command name
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"extract", "read the form values from the PDFs and save them as a csv", runExtract},
+	{"validate", "summarise and validate the marks in an existing raw form values csv", runValidate},
+	{"report", "extract the form values and then validate them (extract + validate)", runReport},
+	{"inspect", "print the header details and form fields of a single PDF", runInspect},
+	{"checks", "collect the scan/heading/filename check reports from the PDFs", runChecks},
+	{"doublemark", "compare the marks given by two markers to the same scripts, and propose agreed marks", runDoubleMark},
+	{"progress", "show how far each marker has got, from the PDFs or an existing raw csv", runProgress},
+	{"markers", "compare each marker's marks on each part with the marks from the other markers", runMarkers},
+	{"watch", "keep watching the folder, and update the reports as new or changed PDFs arrive", runWatch},
+	{"serve", "show the marking status in a web browser, updated as the PDFs change", runServe},
+	{"stamp", "save a copy of each script with a cover page summarising its marks", runStamp},
+	{"feedback", "save a feedback pack for each fully marked script, with the markers' identities removed", runFeedback},
+	{"identify", "join the final marks with the students' details from the Learn ingest report", runIdentify},
+}
+
 func main() {
 
-	var inputDir string
-	flag.StringVar(&inputDir, "inputdir", "./", "path of the folder containing the PDF files to be processed (if in multimarker mode, will also check sub-folders with 'marker' in their name")
-	
-	var partsCSV string
-	flag.StringVar(&partsCSV, "parts", "../parts_and_marks.csv", "path to the csv of parts and marks")
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "--help" || name == "help" {
+		printUsage()
+		return
+	}
 
-	flag.Parse()
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Println("Unknown command:", name)
+	printUsage()
+	os.Exit(2)
+
+}
+
+func printUsage() {
+	fmt.Println("Usage: gradex-extract <command> [flags]\n\nCommands:")
+	for _, cmd := range commands {
+		fmt.Printf("  %-10s %s\n", cmd.name, cmd.usage)
+	}
+}
 
-	
+// the flags that are shared by the commands which work on a folder of scripts
+func inputDirFlag(fs *flag.FlagSet, inputDir *string) {
+	fs.StringVar(inputDir, "inputdir", "./", "path of the folder containing the PDF files to be processed (if in multimarker mode, will also check sub-folders with 'marker' in their name")
+}
+
+func partsFlag(fs *flag.FlagSet, partsCSV *string) {
+	fs.StringVar(partsCSV, "parts", "../parts_and_marks.csv", "path to the csv of parts and marks")
+}
+
+func jobsFlag(fs *flag.FlagSet, workers *int) {
+	fs.IntVar(workers, "j", runtime.NumCPU(), "number of PDFs to extract at once")
+}
+
+func configFlag(fs *flag.FlagSet, configJSON *string) {
+	fs.StringVar(configJSON, "config", "", "path to a course config json with the filename and header patterns (default: the standard exam number format)")
+}
+
+func cacheFlag(fs *flag.FlagSet, noCache *bool) {
+	fs.BoolVar(noCache, "nocache", false, "read every PDF again, rather than only the new or changed ones since the last run")
+}
+
+// cachePath is where the extracted values are kept between runs, next to the raw form values csv
+func cachePath(outputDir string, noCache bool) string {
+	if noCache {
+		return ""
+	}
+	return filepath.Join(outputDir, pdf.CacheFilename)
+}
+
+// printChanges lists the scripts that have changed since the last run
+func printChanges(changes pdf.ScriptChanges) {
+	for _, path := range changes.New {
+		fmt.Println(" + new:", path)
+	}
+	for _, path := range changes.Changed {
+		fmt.Println(" * changed:", path)
+	}
+	for _, path := range changes.Removed {
+		fmt.Println(" - removed:", path)
+	}
+}
+
+// loadConventions reads the course config, if there is one
+func loadConventions(configJSON string) (*pdf.Conventions, error) {
+	if configJSON == "" {
+		return pdf.DefaultConventions(), nil
+	}
+	return pdf.LoadCourseConfig(configJSON)
+}
+
+func roundingFlag(fs *flag.FlagSet, rounding *string) {
+	fs.StringVar(rounding, "round", "none", "how to round script totals when half marks are used: none, nearest, up or down")
+}
+
+func histogramFlag(fs *flag.FlagSet, width *float64) {
+	fs.Float64Var(width, "bands", 10, "width of each band of the histogram of script totals, as a percentage of the marks available")
+}
+
+func reportTime() string {
+	return time.Now().Format("2006-01-02-15-04-05")
+}
+
+func checkInputDir(inputDir string) error {
 	if _, err := os.Stat(inputDir); os.IsNotExist(err) {
 		// inputDir does not exist
-		fmt.Println(err)
-		os.Exit(1)
+		return err
 	}
-	
+	return nil
+}
+
+// loadParts reads the csv of parts and marks
+func loadParts(inputDir string, partsCSV string) ([]*pdf.PaperStructure, error) {
+
 	// see if the default CSV value needs to be changed - in multimarker mode, we expect it to be in the inputDir itself
 	if partsCSV == "../parts_and_marks.csv" {
 		if _, err := os.Stat(partsCSV); os.IsNotExist(err) {
 			partsCSV = inputDir+"/parts_and_marks.csv"
 		}
 	}
-	
+
 	if _, err := os.Stat(partsCSV); os.IsNotExist(err) {
-		fmt.Println("Could not locate", partsCSV)
-		os.Exit(1)
+		return nil, fmt.Errorf("could not locate %s", partsCSV)
 	}
 	parts := pdf.GetPartsAndMarks(partsCSV)
 	pdf.PrettyPrintStruct(parts)
-	
-	
-	report_time := time.Now().Format("2006-01-02-15-04-05")
-
-	// Look at all PDFs in inputDir (including subdirectories)
-	fmt.Println("Looking at input directory: ",inputDir)
-	
-	// Read the raw form values, and save them as a csv
-	csv_path := fmt.Sprintf("%s/01_raw_form_values-%s.csv", inputDir, report_time)
-	form_values := pdf.ReadFormsInDirectory(inputDir, csv_path)
-	
-	// Check the scripts are all from the same course
+
+	return parts, nil
+}
+
+// checkSingleCourse checks the scripts are all from the same course
+func checkSingleCourse(form_values []pdf.FormValues) error {
 	coursecode := make(map[string]bool)
 	for _, entry := range form_values {
 		coursecode[entry.CourseCode] = true
 	}
 	if len(coursecode) != 1 {
-		fmt.Println("Error - found scripts from multiple courses:",coursecode)
-		os.Exit(1)
+		return fmt.Errorf("found scripts from multiple courses: %v", coursecode)
 	}
+	return nil
+}
 
-	// Now summarise the marks and perform validation checks
-	csv_path = fmt.Sprintf("%s/00_marks_summary-%s.csv", inputDir, report_time)
-	pdf.ValidateMarking(form_values, parts, csv_path)
+func gradesFlag(fs *flag.FlagSet, gradesJSON *string) {
+	fs.StringVar(gradesJSON, "grades", "", "path to a grade boundaries json, to add a percentage and grade for each script")
+}
 
-	os.Exit(1)
+// loadGrades reads the grade boundaries, if there are any
+func loadGrades(gradesJSON string) (pdf.GradeBoundaries, error) {
+	if gradesJSON == "" {
+		return nil, nil
+	}
+	return pdf.LoadGradeBoundaries(gradesJSON)
+}
 
+func scalingFlag(fs *flag.FlagSet, scalingJSON *string) {
+	fs.StringVar(scalingJSON, "scaling", "", "path to a scaling json approved by the exam board, to add a scaled total for each script")
+}
+
+// loadScaling reads the scaling, if there is any, and checks it against the parts of the paper
+func loadScaling(scalingJSON string, parts []*pdf.PaperStructure) (*pdf.Scaling, error) {
+	if scalingJSON == "" {
+		return nil, nil
+	}
+	scaling, err := pdf.LoadScaling(scalingJSON)
+	if err != nil {
+		return nil, err
+	}
+	return scaling, scaling.CheckParts(parts)
 }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func runIdentify(args []string) error {
+
+	fs := flag.NewFlagSet("identify", flag.ExitOnError)
+
+	var inputDir string
+	inputDirFlag(fs, &inputDir)
+
+	var workers int
+	jobsFlag(fs, &workers)
+
+	var configJSON string
+	configFlag(fs, &configJSON)
+
+	var noCache bool
+	cacheFlag(fs, &noCache)
+
+	var partsCSV string
+	partsFlag(fs, &partsCSV)
+
+	var rounding string
+	roundingFlag(fs, &rounding)
+
+	var gradesJSON string
+	gradesFlag(fs, &gradesJSON)
+
+	var scalingJSON string
+	scalingFlag(fs, &scalingJSON)
+
+	var rawCSV string
+	fs.StringVar(&rawCSV, "raw", "", "path to an existing raw form values csv to use instead of reading the PDFs again")
+
+	var ingestCSV string
+	fs.StringVar(&ingestCSV, "ingest", "", "path to the Learn ingest report with each student's exam number and matriculation number")
+
+	var outputDir string
+	fs.StringVar(&outputDir, "outputdir", "", "folder to save the identified marks in (default: next to the inputdir, with -identified on the end of its name)")
+
+	fs.Parse(args)
+
+	if ingestCSV == "" {
+		return errors.New("give the Learn ingest report with -ingest")
+	}
+
+	var opts pdf.ValidationOptions
+	var err error
+	if opts.TotalRounding, err = pdf.ParseRounding(rounding); err != nil {
+		return err
+	}
+	if opts.Grades, err = loadGrades(gradesJSON); err != nil {
+		return err
+	}
+
+	// keep the students' details out of the inputdir, which the markers can see
+	if outputDir == "" {
+		abs, err := filepath.Abs(inputDir)
+		if err != nil {
+			return err
+		}
+		outputDir = abs + "-identified"
+	}
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	report_time := reportTime()
+	partsDir := inputDir
+	var form_values []pdf.FormValues
+	if rawCSV != "" {
+		partsDir = filepath.Dir(rawCSV)
+		form_values, err = pdf.ReadFormValuesCSV(rawCSV)
+	} else {
+		form_values, err = extractForms(inputDir, inputDir, workers, configJSON, noCache, report_time)
+	}
+	if err != nil {
+		return err
+	}
+
+	parts, err := loadParts(partsDir, partsCSV)
+	if err != nil {
+		return err
+	}
+	if opts.Scaling, err = loadScaling(scalingJSON, parts); err != nil {
+		return err
+	}
+	if err := checkSingleCourse(form_values); err != nil {
+		return err
+	}
+	summary := pdf.SummariseMarking(form_values, parts, opts)
+
+	// the marks are only identified once marking is finished, so that markers never see who they are marking
+	if unfinished := len(summary.Scripts) - summary.Statistics.Complete; unfinished > 0 {
+		for _, script := range summary.Scripts {
+			if script.Status != pdf.StatusComplete {
+				fmt.Printf(" - %s is %s\n", script.ExamNumber, script.Status)
+			}
+		}
+		return fmt.Errorf("%d scripts are not completely marked yet, so the marks have not been identified", unfinished)
+	}
+
+	identified, err := pdf.IdentifyMarks(summary, ingestCSV)
+	if err != nil {
+		return err
+	}
+
+	identifiedCSV := fmt.Sprintf("%s/10_identified_marks-%s.csv", outputDir, report_time)
+	if err := pdf.WriteIdentifiedMarksCSV(identified, identifiedCSV); err != nil {
+		return err
+	}
+	fmt.Printf("Saved the marks for %d scripts, with the students' details, as %s\n", len(summary.Scripts), identifiedCSV)
+
+	problems := len(identified.Unmatched) + len(identified.NotMarked) + len(identified.Duplicates)
+	if problems == 0 {
+		return nil
+	}
+	for _, examno := range identified.Unmatched {
+		fmt.Printf(" - %s was marked, but is not in the ingest report\n", examno)
+	}
+	for _, examno := range identified.NotMarked {
+		fmt.Printf(" - %s is in the ingest report, but has no marked script\n", examno)
+	}
+	for _, examno := range identified.Duplicates {
+		fmt.Printf(" - %s has more than one submission in the ingest report\n", examno)
+	}
+	return pdf.WriteUnmatchedCSV(identified, fmt.Sprintf("%s/10_unmatched_exam_numbers-%s.csv", outputDir, report_time))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+func runInspect(args []string) error {
+
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+
+	var inputPDF string
+	fs.StringVar(&inputPDF, "file", "", "path of the PDF to inspect")
+
+	var fieldName string
+	fs.StringVar(&fieldName, "field", "", "full name of a single field to print (default: print all fields)")
+
+	var configJSON string
+	configFlag(fs, &configJSON)
+
+	fs.Parse(args)
+
+	// allow the file to be given without the flag, as in: gradex-extract inspect script.pdf
+	if inputPDF == "" && fs.NArg() > 0 {
+		inputPDF = fs.Arg(0)
+	}
+	if inputPDF == "" {
+		return errors.New("specify the PDF to inspect with -file")
+	}
+
+	conv, err := loadConventions(configJSON)
+	if err != nil {
+		return err
+	}
+
+	// read the header and the fields from a single parse of the PDF
+	script, err := pdf.OpenScript(inputPDF)
+	if err != nil {
+		return err
+	}
+	defer script.Close()
+
+	vals, err := pdf.ReadFormFromScript(script, false, conv)
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		pdf.PrettyPrintStruct(vals[0])
+	}
+
+	return script.PrintFields(fieldName)
+}
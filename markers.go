@@ -0,0 +1,54 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+)
+
+func runMarkers(args []string) error {
+
+	fs := flag.NewFlagSet("markers", flag.ExitOnError)
+
+	var rawCSV string
+	fs.StringVar(&rawCSV, "raw", "", "path to an existing raw form values csv (01_raw_form_values-*.csv)")
+
+	var partsCSV string
+	partsFlag(fs, &partsCSV)
+
+	var threshold float64
+	fs.Float64Var(&threshold, "z", 2, "flag a marker as lenient or severe on a part when their mean is more than this many standard errors from the other markers' mean")
+
+	var outputDir string
+	fs.StringVar(&outputDir, "outputdir", "", "folder to write the marker comparison to (default: the folder containing the raw csv)")
+
+	fs.Parse(args)
+
+	if rawCSV == "" {
+		return errors.New("specify the raw form values csv with -raw")
+	}
+	if outputDir == "" {
+		outputDir = filepath.Dir(rawCSV)
+	}
+
+	parts, err := loadParts(outputDir, partsCSV)
+	if err != nil {
+		return err
+	}
+
+	form_values, err := pdf.ReadFormValuesCSV(rawCSV)
+	if err != nil {
+		return err
+	}
+
+	comparisons := pdf.CompareMarkers(form_values, parts, threshold)
+	for _, c := range comparisons {
+		if c.Flag != "" {
+			fmt.Printf("%s looks %s on %s: mean %.2f compared with %.2f from the others (z = %.2f)\n", c.Marker, c.Flag, c.Part, c.Mean, c.OthersMean, c.Z)
+		}
+	}
+
+	return pdf.WriteMarkerComparisonCSV(comparisons, fmt.Sprintf("%s/07_marker_comparison-%s.csv", outputDir, reportTime()))
+}
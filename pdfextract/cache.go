@@ -0,0 +1,115 @@
+package pdfextract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The cache is kept next to the raw form values csv, so that only new or changed scripts are read on the next run
+const CacheFilename = ".gradex-extract-cache.json"
+
+// extractionCache keeps the form values extracted from each script, keyed by the SHA-256 of the PDF
+type extractionCache struct {
+	Conventions string                  `json:"conventions"` // the patterns the values were extracted with
+	Files       map[string]string       `json:"files"`       // files[path] = hash, at the last run
+	Scripts     map[string][]FormValues `json:"scripts"`     // scripts[hash] = form values
+}
+
+// ScriptChanges lists the scripts that have changed since the last run
+type ScriptChanges struct {
+	New       []string // paths of scripts that were not there last time
+	Changed   []string // paths of scripts whose contents have changed
+	Removed   []string // paths of scripts that are no longer there
+	Unchanged int      // number of scripts read from the cache
+}
+
+func (changes ScriptChanges) Any() bool {
+	return len(changes.New)+len(changes.Changed)+len(changes.Removed) > 0
+}
+
+// loadExtractionCache reads the cache at path, starting afresh if there is none, it cannot be read,
+// or it was made with different conventions
+func loadExtractionCache(path string, conventions string) *extractionCache {
+	cache := &extractionCache{Conventions: conventions, Files: map[string]string{}, Scripts: map[string][]FormValues{}}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	saved := extractionCache{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		fmt.Println(" - Ignoring unreadable cache", path, err)
+		return cache
+	}
+	if saved.Files != nil {
+		cache.Files = saved.Files
+	}
+	if saved.Scripts != nil && saved.Conventions == conventions {
+		cache.Scripts = saved.Scripts
+	}
+	return cache
+}
+
+func (cache *extractionCache) save(path string) error {
+	json_data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, json_data, os.ModePerm)
+}
+
+// hashFile gives the SHA-256 of the file's contents, in hex
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// compare works out which scripts have changed, given the hash of each script found on this run
+func (cache *extractionCache) compare(hashes map[string]string) ScriptChanges {
+	changes := ScriptChanges{}
+	for path, hash := range hashes {
+		previous, seen := cache.Files[path]
+		switch {
+		case !seen:
+			changes.New = append(changes.New, path)
+		case previous != hash:
+			changes.Changed = append(changes.Changed, path)
+		}
+	}
+	for path := range cache.Files {
+		if _, ok := hashes[path]; !ok {
+			changes.Removed = append(changes.Removed, path)
+		}
+	}
+	sort.Strings(changes.New)
+	sort.Strings(changes.Changed)
+	sort.Strings(changes.Removed)
+	return changes
+}
+
+// fingerprint describes the patterns, so that a cache made with other patterns is not used
+func (conv *Conventions) fingerprint() string {
+	patterns := []string{}
+	for _, re := range conv.filename {
+		patterns = append(patterns, re.String())
+	}
+	patterns = append(patterns, "")
+	for _, re := range conv.header {
+		patterns = append(patterns, re.String())
+	}
+	return strings.Join(patterns, "\n")
+}
@@ -0,0 +1,64 @@
+package pdfextract
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExtractionCache(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gradex-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "B000001-script.pdf")
+	if err := ioutil.WriteFile(script, []byte("%PDF-1.4 first version"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := hashFile(script)
+	if err != nil || len(hash) != 64 {
+		t.Fatalf("hashFile() = %q, %v", hash, err)
+	}
+
+	cache_path := filepath.Join(dir, CacheFilename)
+	fingerprint := DefaultConventions().fingerprint()
+	cache := loadExtractionCache(cache_path, fingerprint)
+	if len(cache.Files)+len(cache.Scripts) != 0 {
+		t.Errorf("expected an empty cache, got %+v", cache)
+	}
+
+	changes := cache.compare(map[string]string{script: hash})
+	if !reflect.DeepEqual(changes.New, []string{script}) || len(changes.Changed)+len(changes.Removed) != 0 {
+		t.Errorf("first run: %+v", changes)
+	}
+	cache.Files = map[string]string{script: hash}
+	cache.Scripts = map[string][]FormValues{hash: {markEntry("B000001", "GK", 1, 0, "3")}}
+	if err := cache.save(cache_path); err != nil {
+		t.Fatal(err)
+	}
+
+	// the values come back for the same contents, and the script shows as changed once its contents change
+	cache = loadExtractionCache(cache_path, fingerprint)
+	if vals := cache.Scripts[hash]; len(vals) != 1 || vals[0].Value != "3" {
+		t.Errorf("got cached values %+v", vals)
+	}
+	ioutil.WriteFile(script, []byte("%PDF-1.4 second version"), 0644)
+	new_hash, _ := hashFile(script)
+	changes = cache.compare(map[string]string{script: new_hash, filepath.Join(dir, "B000002-script.pdf"): "abc"})
+	if !reflect.DeepEqual(changes.Changed, []string{script}) || len(changes.New) != 1 || !changes.Any() {
+		t.Errorf("second run: %+v", changes)
+	}
+	if changes = cache.compare(map[string]string{}); !reflect.DeepEqual(changes.Removed, []string{script}) {
+		t.Errorf("removed: %+v", changes)
+	}
+
+	// values extracted with other patterns are not used
+	if cache = loadExtractionCache(cache_path, "other patterns"); len(cache.Scripts) != 0 || len(cache.Files) != 1 {
+		t.Errorf("cache with other conventions: %+v", cache)
+	}
+}
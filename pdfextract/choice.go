@@ -0,0 +1,153 @@
+package pdfextract
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A set of questions from which students answer a given number, e.g. "answer 3 of 5"
+// In the parts csv, every part of the optional questions has the same choice (the group name) and choose (how many questions to answer)
+type ChoiceGroup struct {
+	Name      string
+	Choose    int
+	Questions []PartGroup
+}
+
+// ChoiceGroups gives the choice groups declared in the parts csv, in paper order
+func ChoiceGroups(parts []*PaperStructure) []ChoiceGroup {
+	choices := []ChoiceGroup{}
+	index := make(map[string]int)
+	for _, part := range markedParts(parts) {
+		if part.Choice == "" {
+			continue
+		}
+		if _, ok := index[part.Choice]; !ok {
+			index[part.Choice] = len(choices)
+			choices = append(choices, ChoiceGroup{Name: part.Choice})
+		}
+		if part.Choose > choices[index[part.Choice]].Choose {
+			choices[index[part.Choice]].Choose = part.Choose
+		}
+	}
+	for i := range choices {
+		choices[i].Questions = QuestionGroups(choiceParts(parts, choices[i].Name))
+		if choices[i].Choose <= 0 {
+			choices[i].Choose = len(choices[i].Questions)
+		}
+	}
+	return choices
+}
+
+func choiceParts(parts []*PaperStructure, choice string) []*PaperStructure {
+	in_choice := []*PaperStructure{}
+	for _, part := range markedParts(parts) {
+		if part.Choice == choice {
+			in_choice = append(in_choice, part)
+		}
+	}
+	return in_choice
+}
+
+// Marks available for the choice group: the highest scoring questions that can be chosen
+func (choice ChoiceGroup) Marks() float64 {
+	available := []float64{}
+	for _, qn := range choice.Questions {
+		available = append(available, qn.Marks)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(available)))
+	marks := 0.0
+	for i, m := range available {
+		if i < choice.Choose {
+			marks = marks + m
+		}
+	}
+	return marks
+}
+
+// OutOf gives the marks available for the paper (or a section of it), counting only the number
+// of questions that can be chosen from each choice group
+func OutOf(parts []*PaperStructure) float64 {
+	outof := 0.0
+	for _, part := range markedParts(parts) {
+		if part.Choice == "" {
+			outof = outof + part.Marks
+		}
+	}
+	for _, choice := range ChoiceGroups(parts) {
+		outof = outof + choice.Marks()
+	}
+	return outof
+}
+
+// The marks counted from one script for a choice group
+type choiceScore struct {
+	Total     float64
+	Attempted []string // the questions with at least one mark
+	Dropped   []string // the attempted questions that are not counted, because too many were answered
+}
+
+// score picks out the best questions from those the student attempted
+func (choice ChoiceGroup) score(marks_by_part map[string][]string) choiceScore {
+	type attempt struct {
+		question PartGroup
+		total    float64
+	}
+	attempts := []attempt{}
+	for _, qn := range choice.Questions {
+		attempted := false
+		total := 0.0
+		for _, pname := range qn.Parts {
+			if len(marks_by_part[pname]) > 0 {
+				attempted = true
+				total = total + sumOfMarks(marks_by_part[pname])
+			}
+		}
+		if attempted {
+			attempts = append(attempts, attempt{qn, total})
+		}
+	}
+
+	result := choiceScore{}
+	for _, a := range attempts {
+		result.Attempted = append(result.Attempted, a.question.Name)
+	}
+
+	// best marks first, keeping paper order for ties
+	sort.SliceStable(attempts, func(i, j int) bool { return attempts[i].total > attempts[j].total })
+	for i, a := range attempts {
+		if i < choice.Choose {
+			result.Total = result.Total + a.total
+		} else {
+			result.Dropped = append(result.Dropped, a.question.Name)
+		}
+	}
+	sort.Strings(result.Dropped)
+	return result
+}
+
+// problem describes what is wrong with the number of questions answered (if anything)
+func (choice ChoiceGroup) problem(result choiceScore) string {
+	answered := len(result.Attempted)
+	switch {
+	case answered == 0:
+		return fmt.Sprintf("no questions answered (choose %d)", choice.Choose)
+	case answered < choice.Choose:
+		return fmt.Sprintf("answered %d of %d questions (%s)", answered, choice.Choose, strings.Join(result.Attempted, ", "))
+	case answered > choice.Choose:
+		return fmt.Sprintf("answered %d questions, best %d counted (%s dropped)", answered, choice.Choose, strings.Join(result.Dropped, ", "))
+	}
+	return ""
+}
+
+// questionOf finds the question that an optional part belongs to
+func (choice ChoiceGroup) questionOf(pname string) (PartGroup, bool) {
+	for _, qn := range choice.Questions {
+		for _, p := range qn.Parts {
+			if p == pname {
+				return qn, true
+			}
+		}
+	}
+	return PartGroup{}, false
+}
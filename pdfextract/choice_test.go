@@ -0,0 +1,41 @@
+package pdfextract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChoiceScoring(t *testing.T) {
+
+	parts := []*PaperStructure{
+		{Part: "1", Marks: 10},
+		{Part: "2a", Marks: 5, Choice: "B", Choose: 2},
+		{Part: "2b", Marks: 5, Choice: "B", Choose: 2},
+		{Part: "3", Marks: 10, Choice: "B", Choose: 2},
+		{Part: "4", Marks: 20, Choice: "B", Choose: 2},
+	}
+
+	if got := OutOf(parts); got != 40 {
+		t.Errorf("OutOf() = %v; want 40 (1, plus the best two of 2, 3 and 4)", got)
+	}
+
+	choice := ChoiceGroups(parts)[0]
+
+	too_many := choice.score(map[string][]string{"2a": {"4"}, "2b": {"3"}, "3": {"5"}, "4": {"6.5"}})
+	if too_many.Total != 13.5 || !reflect.DeepEqual(too_many.Dropped, []string{"Q3"}) {
+		t.Errorf("best 2 of 3: got %+v", too_many)
+	}
+	if got := choice.problem(too_many); got != "answered 3 questions, best 2 counted (Q3 dropped)" {
+		t.Errorf("problem() = %q", got)
+	}
+
+	just_right := choice.score(map[string][]string{"2a": {"0"}, "4": {"12"}})
+	if just_right.Total != 12 || choice.problem(just_right) != "" {
+		t.Errorf("2 of 3: got %+v, %q", just_right, choice.problem(just_right))
+	}
+
+	too_few := choice.score(map[string][]string{"3": {"7"}})
+	if got := choice.problem(too_few); got != "answered 1 of 2 questions (Q3)" {
+		t.Errorf("problem() = %q", got)
+	}
+}
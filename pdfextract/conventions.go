@@ -0,0 +1,173 @@
+package pdfextract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// Course config, read from a json file, describing how exam numbers appear in the filenames and the script headers, e.g.
+//
+//	{
+//		"filename_patterns": ["(?P<exam>B[0-9]{6})-.*\\.pdf", "(?P<exam>R[0-9]{5})_resit\\.pdf"],
+//		"header_patterns": ["^(?P<course>[A-Z]{4}[0-9]{5}) (?P<exam>[A-Z][0-9]+)\\n(?P<marker>[A-Za-z]+)\\n"]
+//	}
+//
+// Filename patterns need an "exam" group. Header patterns can use any of the "course", "exam" and "marker" groups,
+// and each of these is taken from the first header pattern that finds it.
+type CourseConfig struct {
+	FilenamePatterns []string `json:"filename_patterns"`
+	HeaderPatterns   []string `json:"header_patterns"`
+}
+
+// The compiled patterns from a CourseConfig
+type Conventions struct {
+	filename []*regexp.Regexp
+	header   []*regexp.Regexp
+}
+
+// The conventions used before course configs were added
+var defaultCourseConfig = CourseConfig{
+	FilenamePatterns: []string{"(?P<exam>B[0-9]{6})-.*.pdf"},
+	HeaderPatterns: []string{
+		"(?P<course>[a-zA-Z0-9]+) ",    // course code is the first word of text https://regex101.com/r/9GjHTM/10
+		" (?P<exam>[a-zA-Z0-9]+)\n",    // exam number is the last word on the first line https://regex101.com/r/9GjHTM/11
+		".*\n(?P<marker>[a-zA-Z]+)\n", // initials appear as the second line of text https://regex101.com/r/9GjHTM/9
+	},
+}
+
+func DefaultConventions() *Conventions {
+	conv, err := defaultCourseConfig.Compile()
+	check(err)
+	return conv
+}
+
+// LoadCourseConfig reads a course config file - any list of patterns it leaves out is taken from the defaults
+func LoadCourseConfig(path string) (*Conventions, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := CourseConfig{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(config.FilenamePatterns) == 0 {
+		config.FilenamePatterns = defaultCourseConfig.FilenamePatterns
+	}
+	if len(config.HeaderPatterns) == 0 {
+		config.HeaderPatterns = defaultCourseConfig.HeaderPatterns
+	}
+	conv, err := config.Compile()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return conv, nil
+}
+
+// Compile checks the patterns and gets them ready to use
+func (config CourseConfig) Compile() (*Conventions, error) {
+	conv := &Conventions{}
+	for _, pattern := range config.FilenamePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if re.SubexpIndex("exam") < 0 {
+			return nil, fmt.Errorf("filename pattern %q has no (?P<exam>...) group", pattern)
+		}
+		conv.filename = append(conv.filename, re)
+	}
+	groups := make(map[string]bool)
+	for _, pattern := range config.HeaderPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range re.SubexpNames() {
+			groups[name] = true
+		}
+		conv.header = append(conv.header, re)
+	}
+	if !groups["course"] || !groups["exam"] {
+		return nil, errors.New("the header patterns need (?P<course>...) and (?P<exam>...) groups")
+	}
+	return conv, nil
+}
+
+// ExamNumberFromFilename gives the exam number in a script's filename, if it follows one of the filename patterns
+func (conv *Conventions) ExamNumberFromFilename(filename string) (string, bool) {
+	for _, re := range conv.filename {
+		if matches := re.FindStringSubmatch(filename); matches != nil {
+			return matches[re.SubexpIndex("exam")], true
+		}
+	}
+	return "", false
+}
+
+// headerField finds the named group in the first header pattern that matches the text of the first page
+func (conv *Conventions) headerField(pdf_text map[int]string, group string) string {
+	// TODO - this could check *all* pages for consistency
+	// but let's be lazy and just use the first page
+	raw_string_p1 := pdf_text[0]
+	for _, re := range conv.header {
+		idx := re.SubexpIndex(group)
+		if idx < 0 {
+			continue
+		}
+		if matches := re.FindStringSubmatch(raw_string_p1); matches != nil && matches[idx] != "" {
+			return matches[idx]
+		}
+	}
+	return ""
+}
+
+// headerLines is the number of lines at the top of the first page that the marker's initials were found in, as
+// extractMarkerInitials finds them, or 0 if there are no initials in the header
+func (conv *Conventions) headerLines(pdf_text map[int]string) int {
+	raw_string_p1 := pdf_text[0]
+	for _, re := range conv.header {
+		idx := re.SubexpIndex("marker")
+		if idx < 0 {
+			continue
+		}
+		if loc := re.FindStringSubmatchIndex(raw_string_p1); loc != nil && loc[2*idx] < loc[2*idx+1] {
+			return strings.Count(raw_string_p1[:loc[2*idx+1]], "\n") + 1
+		}
+	}
+	return 0
+}
+
+// topLines gives the first n lines of text, keeping the newline at the end of each
+func topLines(text string, n int) string {
+	end := 0
+	for i := 0; i < n; i++ {
+		next := strings.Index(text[end:], "\n")
+		if next < 0 {
+			return text
+		}
+		end = end + next + 1
+	}
+	return text[:end]
+}
+
+func (conv *Conventions) extractMarkerInitials(pdf_text map[int]string) string {
+	return conv.headerField(pdf_text, "marker")
+}
+
+func (conv *Conventions) extractCourseCode(pdf_text map[int]string) (string, error) {
+	if course := conv.headerField(pdf_text, "course"); course != "" {
+		return course, nil
+	}
+	return "", errors.New("no course code found in the header")
+}
+
+func (conv *Conventions) extractExamNumber(pdf_text map[int]string) (string, error) {
+	if examno := conv.headerField(pdf_text, "exam"); examno != "" {
+		return examno, nil
+	}
+	return "", errors.New("no exam number found in the header")
+}
@@ -0,0 +1,144 @@
+package pdfextract
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/timdrysdale/unipdf/v3/creator"
+	pdf "github.com/timdrysdale/unipdf/v3/model"
+)
+
+// coverText adds a line of text to the page being built
+func coverText(c *creator.Creator, text string, size float64, bold bool) error {
+	p := c.NewParagraph(text)
+	p.SetFontSize(size)
+	if bold {
+		font, err := pdf.NewStandard14Font(pdf.HelveticaBoldName)
+		if err != nil {
+			return err
+		}
+		p.SetFont(font)
+	}
+	p.SetMargins(0, 0, 4, 4)
+	return c.Draw(p)
+}
+
+// marksTable draws a table of the script's marks for each part, subtotal and total, with a column of the
+// validation problems if show_problems is set
+func marksTable(c *creator.Creator, summary *MarksSummary, script ScriptSummary, show_problems bool) error {
+
+	headings := []string{"Part", "Out of", "Mark"}
+	if show_problems {
+		headings = append(headings, "Problem")
+	}
+	table := c.NewTable(len(headings))
+	if show_problems {
+		table.SetColumnWidths(0.2, 0.15, 0.2, 0.45)
+	}
+
+	highlight := creator.ColorRGBFrom8bit(255, 199, 206) // as in the Excel summary
+	addRow := func(values []string, header bool, problem bool) {
+		for _, value := range values {
+			p := c.NewParagraph(value)
+			p.SetMargins(4, 4, 2, 2)
+			if header {
+				if font, err := pdf.NewStandard14Font(pdf.HelveticaBoldName); err == nil {
+					p.SetFont(font)
+				}
+			}
+			cell := table.NewCell()
+			cell.SetBorder(creator.CellBorderSideAll, creator.CellBorderStyleSingle, 0.5)
+			if problem {
+				cell.SetBackgroundColor(highlight)
+			}
+			cell.SetContent(p)
+		}
+	}
+
+	addRow(headings, true, false)
+	for _, column := range summary.Columns() {
+		row := []string{column.Name, formatMark(column.OutOf), summary.Cell(script, column.Name)}
+		problem, has_problem := script.Validation[column.Name]
+		if show_problems {
+			row = append(row, problem)
+		}
+		addRow(row, false, show_problems && has_problem)
+	}
+	for _, column := range summary.TotalColumns() {
+		out_of := ""
+		if strings.HasSuffix(column, "Total") {
+			out_of = formatMark(summary.OutOf)
+		}
+		row := []string{column, out_of, summary.Cell(script, column)}
+		if show_problems {
+			row = append(row, "")
+		}
+		addRow(row, true, false)
+	}
+	return c.Draw(table)
+}
+
+// StampCoverPage saves a copy of the script at script_path with a cover page showing the marks for each part, the
+// total and the validation status, so that the script is self-describing. The form fields are kept.
+func StampCoverPage(script_path string, summary *MarksSummary, script ScriptSummary, output_path string) error {
+
+	s, err := OpenScript(script_path)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSizeA4)
+	c.NewPage()
+
+	lines := []struct {
+		text string
+		size float64
+		bold bool
+	}{
+		{"Marks summary", 18, true},
+		{fmt.Sprintf("%s   %s", summary.Course, script.ExamNumber), 14, true},
+		{"Status: " + script.Status, 11, false},
+		{"Markers: " + sliceToCommaString(script.Markers), 11, false},
+	}
+	for _, line := range lines {
+		if err := coverText(c, line.text, line.size, line.bold); err != nil {
+			return err
+		}
+	}
+	if err := marksTable(c, summary, script, true); err != nil {
+		return err
+	}
+	notes := []string{}
+	if problems := script.ValidationString(); problems != "" {
+		notes = append(notes, "Validation: "+problems)
+	}
+	if len(script.UnmarkedPages) > 0 {
+		notes = append(notes, "Unmarked pages: "+pageList(script.UnmarkedPages))
+	}
+	if len(script.BadPages) > 0 {
+		notes = append(notes, "Bad pages: "+pageList(script.BadPages))
+	}
+	notes = append(notes, "Summarised at "+time.Now().Format("15:04 on 2 Jan 2006")+" from "+filepath.Base(script_path))
+	for _, note := range notes {
+		if err := coverText(c, note, 10, false); err != nil {
+			return err
+		}
+	}
+
+	// then the script itself, with its form
+	for _, page := range s.reader.PageList {
+		if err := c.AddPage(page); err != nil {
+			return err
+		}
+	}
+	if s.reader.AcroForm != nil {
+		if err := c.SetForms(s.reader.AcroForm); err != nil {
+			return err
+		}
+	}
+	return c.WriteToFile(output_path)
+}
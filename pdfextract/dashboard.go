@@ -0,0 +1,263 @@
+package pdfextract
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dashboard serves web pages showing the current state of the marking. It is updated with Update
+// each time the scripts are read, and the pages show the latest update.
+type Dashboard struct {
+	mu            sync.RWMutex
+	updated       time.Time
+	summary       *MarksSummary
+	progress      []MarkerProgress
+	script_errors []ScriptError
+	fields        map[string][]FormValues // fields[ExamNo] = the form values from that script
+	pdfs          map[string][]string     // pdfs[ExamNo] = paths of every copy of the script, e.g. one for each marker
+}
+
+// Update replaces what the dashboard shows. pdfs gives the paths of every copy of each script, keyed by exam number.
+func (d *Dashboard) Update(form_values []FormValues, script_errors []ScriptError, parts []*PaperStructure, opts ValidationOptions, pdfs map[string][]string) {
+	summary := SummariseMarking(form_values, parts, opts)
+	progress := MarkerProgressReport(form_values, parts)
+	fields := make(map[string][]FormValues)
+	for _, entry := range form_values {
+		if entry.Field != "" {
+			fields[entry.ExamNumber] = append(fields[entry.ExamNumber], entry)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.updated = time.Now()
+	d.summary = summary
+	d.progress = progress
+	d.script_errors = script_errors
+	d.fields = fields
+	d.pdfs = pdfs
+}
+
+// Handler gives the pages of the dashboard:
+//
+//	/                       the marks summary, validation problems, marker progress and part statistics
+//	/script/<exam number>   the marks and form fields of one script, page by page
+//	/pdf/<exam number>/<n>  the nth copy of the script itself (counting from 1)
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.serveOverview)
+	mux.HandleFunc("/script/", d.serveScript)
+	mux.HandleFunc("/pdf/", d.servePDF)
+	return mux
+}
+
+func (d *Dashboard) serveOverview(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.summary == nil {
+		w.Write([]byte("<p>Reading the scripts - refresh in a moment.</p>"))
+		return
+	}
+	data := struct {
+		Updated      string
+		Summary      *MarksSummary
+		Columns      []ColumnSummary
+		Invalid      []ScriptSummary
+		Complete     []ScriptSummary
+		Unmarked     []ScriptSummary
+		Progress     []MarkerProgress
+		ScriptErrors []ScriptError
+	}{
+		Updated:      d.updated.Format("15:04:05 on 2 Jan 2006"),
+		Summary:      d.summary,
+		Columns:      d.summary.Columns(),
+		Invalid:      d.summary.ScriptsWithStatus(StatusInvalid),
+		Complete:     d.summary.ScriptsWithStatus(StatusComplete),
+		Unmarked:     d.summary.ScriptsWithStatus(StatusUnmarked),
+		Progress:     d.progress,
+		ScriptErrors: d.script_errors,
+	}
+	if err := overviewTemplate.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// A page of a script, with the form fields on it
+type dashboardPage struct {
+	Page   int
+	Fields []FormValues
+}
+
+func (d *Dashboard) serveScript(w http.ResponseWriter, r *http.Request) {
+	ExamNo := strings.TrimPrefix(r.URL.Path, "/script/")
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.summary == nil {
+		http.NotFound(w, r)
+		return
+	}
+	var script *ScriptSummary
+	for i := range d.summary.Scripts {
+		if d.summary.Scripts[i].ExamNumber == ExamNo {
+			script = &d.summary.Scripts[i]
+		}
+	}
+	if script == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	by_page := make(map[int][]FormValues)
+	for _, entry := range d.fields[ExamNo] {
+		by_page[entry.Page] = append(by_page[entry.Page], entry)
+	}
+	pages := []dashboardPage{}
+	for page, fields := range by_page {
+		pages = append(pages, dashboardPage{page, fields})
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Page < pages[j].Page })
+
+	cells := []struct{ Name, Value, Problem string }{}
+	for _, column := range d.summary.Columns() {
+		cells = append(cells, struct{ Name, Value, Problem string }{column.Name, d.summary.Cell(*script, column.Name), script.Validation[column.Name]})
+	}
+	for _, column := range d.summary.TotalColumns() {
+		cells = append(cells, struct{ Name, Value, Problem string }{column, d.summary.Cell(*script, column), ""})
+	}
+
+	data := struct {
+		Script ScriptSummary
+		Cells  []struct{ Name, Value, Problem string }
+		Pages  []dashboardPage
+		PDFs   []string
+	}{*script, cells, pages, d.pdfs[ExamNo]}
+	if err := scriptTemplate.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (d *Dashboard) servePDF(w http.ResponseWriter, r *http.Request) {
+	ExamNo, copy_number := strings.TrimPrefix(r.URL.Path, "/pdf/"), 1
+	if i := strings.LastIndex(ExamNo, "/"); i >= 0 {
+		n, err := strconv.Atoi(ExamNo[i+1:])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		ExamNo, copy_number = ExamNo[:i], n
+	}
+	d.mu.RLock()
+	paths := d.pdfs[ExamNo]
+	d.mu.RUnlock()
+	if copy_number < 1 || copy_number > len(paths) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	http.ServeFile(w, r, paths[copy_number-1])
+}
+
+// the pages are self-contained, so the dashboard works offline
+const dashboardStyle = `<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+th { background: #eee; }
+.problem { background: #ffc7ce; }
+</style>`
+
+var dashboardFuncs = template.FuncMap{
+	"mark":  formatMark,
+	"mean":  meanString,
+	"pages": pageList,
+	"inc":   func(i int) int { return i + 1 },
+	"cell": func(summary *MarksSummary, script ScriptSummary, column string) string {
+		return summary.Cell(script, column)
+	},
+	"problem": func(script ScriptSummary, column string) bool {
+		_, ok := script.Validation[column]
+		return ok
+	},
+	// table gathers what the "scripts" template needs to show a block of scripts
+	"table": func(summary *MarksSummary, columns []ColumnSummary, scripts []ScriptSummary) interface{} {
+		return struct {
+			Summary *MarksSummary
+			Columns []ColumnSummary
+			Scripts []ScriptSummary
+		}{summary, columns, scripts}
+	},
+}
+
+var overviewTemplate = template.Must(template.New("overview").Funcs(dashboardFuncs).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta http-equiv="refresh" content="30"><title>{{.Summary.Course}} marking</title>` + dashboardStyle + `</head><body>
+<h1>{{.Summary.Course}} marking</h1>
+<p>Updated at {{.Updated}}. Markers: {{range $i, $m := .Summary.Markers}}{{if $i}}, {{end}}{{$m}}{{end}}</p>
+<p>{{.Summary.Statistics.Scripts}} scripts: {{.Summary.Statistics.Complete}} complete, {{.Summary.Statistics.Invalid}} with problems, {{.Summary.Statistics.Unmarked}} yet to be marked.
+{{with .Summary.Statistics.Mean}}Mean total {{mean $.Summary.Statistics.Mean "%.2f"}} out of {{mark $.Summary.OutOf}} ({{mean $.Summary.Statistics.MeanPercent "%.1f"}}%).{{end}}</p>
+
+<h2>Marker progress</h2>
+<table><tr><th>Marker</th><th>Scripts</th><th>Fully marked</th><th>Partially marked</th><th>Untouched</th><th>Pages seen</th><th>Parts outstanding</th></tr>
+{{range .Progress}}<tr><td>{{.Marker}}</td><td>{{.Scripts}}</td><td>{{.FullyMarked}}</td><td>{{.PartiallyMarked}}</td><td>{{.Untouched}}</td><td>{{.PagesSeen}} / {{.Pages}}</td><td>{{.PartsOutstanding}}</td></tr>
+{{end}}</table>
+
+<h2>Part statistics</h2>
+<table><tr><th>Part</th><th>Out of</th><th>Mean</th><th>Mean (%)</th><th>Median</th><th>SD</th><th>Min</th><th>Max</th><th>Zero marks</th><th>Full marks</th></tr>
+{{range .Columns}}<tr><td>{{.Name}}</td><td>{{mark .OutOf}}</td><td>{{mean .Mean "%.2f"}}</td><td>{{mean .MeanPercent "%.1f"}}</td>
+{{with .Distribution}}<td>{{mark .Median}}</td><td>{{printf "%.2f" .SD}}</td><td>{{mark .Min}}</td><td>{{mark .Max}}</td><td>{{.Zero}}</td><td>{{.Full}}</td>{{end}}</tr>
+{{end}}</table>
+
+{{with .Summary.Grades}}<h2>Grade distribution</h2>
+<table><tr><th>Grade</th><th>From (%)</th><th>Scripts</th></tr>
+{{range .}}<tr><td>{{.Grade}}</td><td>{{mark .From}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>{{end}}
+
+{{with .Summary.Statistics.Histogram}}<h2>Distribution of totals</h2>
+<table><tr><th>Band</th><th>Scripts</th></tr>
+{{range .}}<tr><td>{{.Label}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>{{end}}
+
+{{define "scripts"}}<table><tr><th>Exam Number</th>{{range .Columns}}<th>{{.Name}}</th>{{end}}{{range .Summary.TotalColumns}}<th>{{.}}</th>{{end}}<th>Validation</th><th>Unmarked Pages</th></tr>
+{{range $script := .Scripts}}<tr><td><a href="/script/{{$script.ExamNumber}}">{{$script.ExamNumber}}</a></td>
+{{range $.Columns}}<td{{if problem $script .Name}} class="problem"{{end}}>{{cell $.Summary $script .Name}}</td>{{end}}
+{{range $.Summary.TotalColumns}}<td>{{cell $.Summary $script .}}</td>{{end}}<td>{{$script.ValidationString}}</td><td>{{pages $script.UnmarkedPages}}</td></tr>
+{{end}}</table>{{end}}
+
+<h2>Validation problems ({{len .Invalid}})</h2>
+{{template "scripts" (table .Summary .Columns .Invalid)}}
+
+<h2>Marking completed ({{len .Complete}})</h2>
+{{template "scripts" (table .Summary .Columns .Complete)}}
+
+<h2>Yet to be marked ({{len .Unmarked}})</h2>
+<p>{{range .Unmarked}}<a href="/script/{{.ExamNumber}}">{{.ExamNumber}}</a> {{end}}</p>
+
+{{if .ScriptErrors}}<h2>Problems reading scripts ({{len .ScriptErrors}})</h2>
+<table><tr><th>File</th><th>Stage</th><th>Reason</th></tr>
+{{range .ScriptErrors}}<tr><td>{{.File}}</td><td>{{.Stage}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>{{end}}
+</body></html>`))
+
+var scriptTemplate = template.Must(template.New("script").Funcs(dashboardFuncs).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Script.ExamNumber}}</title>` + dashboardStyle + `</head><body>
+<p><a href="/">&larr; back to the summary</a></p>
+<h1>{{.Script.ExamNumber}} ({{.Script.Status}})</h1>
+{{with .PDFs}}<p>Open the script: {{range $i, $path := .}}{{if $i}}, {{end}}<a href="/pdf/{{$.Script.ExamNumber}}/{{inc $i}}">{{$path}}</a>{{end}}</p>{{end}}
+<table><tr>{{range .Cells}}<th>{{.Name}}</th>{{end}}</tr>
+<tr>{{range .Cells}}<td{{if .Problem}} class="problem" title="{{.Problem}}"{{end}}>{{.Value}}</td>{{end}}</tr></table>
+{{with .Script.ValidationString}}<p>Validation: {{.}}</p>{{end}}
+{{with .Script.UnmarkedPages}}<p>Unmarked pages: {{pages .}}</p>{{end}}
+{{range $page := .Pages}}<h2>Page {{.Page}}{{range $i, $path := $.PDFs}} <small><a href="/pdf/{{$.Script.ExamNumber}}/{{inc $i}}#page={{$page.Page}}">view{{if gt (len $.PDFs) 1}} copy {{inc $i}}{{end}}</a></small>{{end}}</h2>
+<table><tr><th>Field</th><th>Marker</th><th>Value</th></tr>
+{{range .Fields}}<tr><td>{{.FieldName}}</td><td>{{.Marker}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}
+</body></html>`))
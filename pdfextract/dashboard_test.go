@@ -0,0 +1,79 @@
+package pdfextract
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDashboard(t *testing.T) {
+
+	d := &Dashboard{}
+	server := httptest.NewServer(d.Handler())
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := server.Client().Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if _, body := get("/"); !strings.Contains(body, "Reading the scripts") {
+		t.Errorf("before the first update: %s", body)
+	}
+
+	form_values := []FormValues{
+		markEntry("B000001", "GK", 1, 0, "3.5"),
+		markEntry("B000001", "GK", 1, 1, "6"),
+		markEntry("B000001", "GK", 2, 2, "7"),
+		markEntry("B000002", "GK", 1, 0, "5"),
+	}
+	// each marker has their own copy of B000002
+	dir, err := ioutil.TempDir("", "gradex-dashboard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	copies := []string{filepath.Join(dir, "GK", "B000002-x.pdf"), filepath.Join(dir, "JS", "B000002-x.pdf")}
+	for _, path := range copies {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte("copy in "+filepath.Base(filepath.Dir(path))), 0644)
+	}
+	d.Update(form_values, []ScriptError{{"B000003-x.pdf", StageOpen, "not a PDF"}}, testParts, ValidationOptions{}, map[string][]string{"B000002": copies})
+
+	status, body := get("/")
+	for _, want := range []string{"MATH01234 marking", `<a href="/script/B000001">`, `class="problem">5</td>`, "Fully marked", "not a PDF"} {
+		if status != 200 || !strings.Contains(body, want) {
+			t.Errorf("overview is missing %q:\n%s", want, body)
+		}
+	}
+
+	status, body = get("/script/B000001")
+	if status != 200 || !strings.Contains(body, "Page 2") || !strings.Contains(body, "qn-part-mark-2") {
+		t.Errorf("script page:\n%s", body)
+	}
+	if status, _ = get("/script/B999999"); status != 404 {
+		t.Errorf("got %d for an unknown script", status)
+	}
+	if status, _ = get("/pdf/B000001"); status != 404 {
+		t.Errorf("got %d for a script without a PDF", status)
+	}
+
+	status, body = get("/script/B000002")
+	if status != 200 || !strings.Contains(body, `href="/pdf/B000002/1"`) || !strings.Contains(body, `href="/pdf/B000002/2"`) {
+		t.Errorf("script page should link to both copies:\n%s", body)
+	}
+	if status, body = get("/pdf/B000002/2"); status != 200 || body != "copy in JS" {
+		t.Errorf("got %d %q for the second copy", status, body)
+	}
+	if status, _ = get("/pdf/B000002/3"); status != 404 {
+		t.Errorf("got %d for a copy that doesn't exist", status)
+	}
+}
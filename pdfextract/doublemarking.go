@@ -0,0 +1,236 @@
+package pdfextract
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gocarina/gocsv"
+)
+
+// How to propose an agreed mark when two markers are within tolerance of each other
+const (
+	AgreeMean   = "mean"   // the mean of the two marks, rounded to the part's granularity
+	AgreeHigher = "higher" // the higher of the two marks
+)
+
+// The status of each row of the double marking report
+const (
+	DoubleAgreed       = "agreed"
+	DoubleDisagree     = "third marker required" // the markers differ by more than the tolerance
+	DoubleMissing      = "missing mark"          // only one of the markers has marked this part
+	DoubleThirdMarked  = "third marked"          // more than two markers, so the median mark is proposed
+	DoubleSingleMarked = "single marked"
+)
+
+// Options for ReconcileDoubleMarking
+type DoubleMarkingOptions struct {
+	PartTolerance  float64 // largest difference between the markers on a part that can be agreed automatically
+	TotalTolerance float64 // largest difference between the markers' totals that can be agreed automatically
+	Agree          string  // AgreeMean or AgreeHigher
+}
+
+// One row of the double marking report - one for each part of each double marked script, then one for the script's total
+type DoubleMarkRow struct {
+	ExamNumber   string `csv:"ExamNumber"`
+	Part         string `csv:"Part"`
+	FirstMarker  string `csv:"FirstMarker"`
+	FirstMark    string `csv:"FirstMark"`
+	SecondMarker string `csv:"SecondMarker"`
+	SecondMark   string `csv:"SecondMark"`
+	OtherMarks   string `csv:"OtherMarks"` // from any further markers, e.g. "JS: 4"
+	Difference   string `csv:"Difference"`
+	Agreed       string `csv:"Agreed"`
+	Status       string `csv:"Status"`
+}
+
+// ReconcileDoubleMarking compares the marks given to each script by different markers (using the marker initials on
+// each page), and proposes an agreed mark for each part and for the total. The first and second markers are the
+// first two to have marked the script, going by the pages their marks are on.
+func ReconcileDoubleMarking(form_values []FormValues, parts []*PaperStructure, opts DoubleMarkingOptions) []DoubleMarkRow {
+
+	part_name := make(map[int]string)
+	part_step := make(map[string]float64)
+	for pnum, part := range parts {
+		if part.Part != "" {
+			part_name[pnum] = part.Label()
+			part_step[part.Label()] = part.Granularity
+		}
+	}
+
+	// marks[ExamNo][part][marker] = 4
+	marks := make(map[string]map[string]map[string]float64)
+	// first_page[ExamNo][marker] = 3 - the first page with a mark from this marker, which puts the markers in the
+	// order they marked the script
+	first_page := make(map[string]map[string]int)
+	for _, entry := range form_values {
+		partnum, moderation, ok := markField(entry.FieldName)
+		if !ok || moderation || !hasContent(entry.Value) {
+			continue
+		}
+		pname, known := part_name[partnum]
+		mark, err := parseMark(entry.Value)
+		if !known || err != nil {
+			continue
+		}
+		ExamNo := entry.ExamNumber
+		if marks[ExamNo] == nil {
+			marks[ExamNo] = make(map[string]map[string]float64)
+			first_page[ExamNo] = make(map[string]int)
+		}
+		if marks[ExamNo][pname] == nil {
+			marks[ExamNo][pname] = make(map[string]float64)
+		}
+		marks[ExamNo][pname][entry.Marker] = marks[ExamNo][pname][entry.Marker] + mark
+		if page, seen := first_page[ExamNo][entry.Marker]; !seen || entry.Page < page {
+			first_page[ExamNo][entry.Marker] = entry.Page
+		}
+	}
+
+	examnos := make([]string, 0, len(marks))
+	for ExamNo := range marks {
+		examnos = append(examnos, ExamNo)
+	}
+	sort.Strings(examnos)
+
+	partnames := make([]string, 0, len(part_name))
+	for pnum := range parts {
+		if pname, ok := part_name[pnum]; ok {
+			partnames = append(partnames, pname)
+		}
+	}
+
+	rows := []DoubleMarkRow{}
+	for _, ExamNo := range examnos {
+		script_markers := make([]string, 0, len(first_page[ExamNo]))
+		for marker := range first_page[ExamNo] {
+			script_markers = append(script_markers, marker)
+		}
+		sort.Slice(script_markers, func(i, j int) bool {
+			pi, pj := first_page[ExamNo][script_markers[i]], first_page[ExamNo][script_markers[j]]
+			if pi != pj {
+				return pi < pj
+			}
+			return script_markers[i] < script_markers[j]
+		})
+		if len(script_markers) < 2 {
+			rows = append(rows, DoubleMarkRow{ExamNumber: ExamNo, Part: "Total", FirstMarker: strings.Join(script_markers, ""), Status: DoubleSingleMarked})
+			continue
+		}
+
+		marker_totals := make(map[string]float64)
+		agreed_total := 0.0
+		all_agreed := true
+		any_missing := false
+		for _, pname := range partnames {
+			by_marker := marks[ExamNo][pname]
+			if len(by_marker) == 0 {
+				continue
+			}
+			for marker, mark := range by_marker {
+				marker_totals[marker] = marker_totals[marker] + mark
+			}
+			row := compareMarks(ExamNo, pname, script_markers, by_marker, opts.PartTolerance, opts.Agree, part_step[pname])
+			if row.Status == DoubleAgreed || row.Status == DoubleThirdMarked {
+				agreed, _ := parseMark(row.Agreed)
+				agreed_total = agreed_total + agreed
+			} else {
+				all_agreed = false
+			}
+			any_missing = any_missing || row.Status == DoubleMissing
+			rows = append(rows, row)
+		}
+
+		total := compareMarks(ExamNo, "Total", script_markers, marker_totals, opts.TotalTolerance, opts.Agree, 0)
+		total.Agreed = ""
+		if all_agreed {
+			total.Agreed = formatMark(agreed_total)
+		}
+		// the total can only be agreed once every part is, and a missing mark needs marking rather than a third marker
+		switch {
+		case any_missing:
+			total.Status = DoubleMissing
+		case total.Status == DoubleAgreed && !all_agreed:
+			total.Status = DoubleDisagree
+		}
+		rows = append(rows, total)
+	}
+
+	return rows
+}
+
+// compareMarks sets out the marks from each marker, and proposes an agreed mark if they are close enough
+func compareMarks(ExamNo string, pname string, script_markers []string, by_marker map[string]float64, tolerance float64, agree string, step float64) DoubleMarkRow {
+
+	row := DoubleMarkRow{ExamNumber: ExamNo, Part: pname, FirstMarker: script_markers[0], SecondMarker: script_markers[1]}
+	first, has_first := by_marker[script_markers[0]]
+	second, has_second := by_marker[script_markers[1]]
+	if has_first {
+		row.FirstMark = formatMark(first)
+	}
+	if has_second {
+		row.SecondMark = formatMark(second)
+	}
+
+	others := []string{}
+	all_marks := []float64{}
+	for _, marker := range script_markers {
+		if mark, ok := by_marker[marker]; ok {
+			all_marks = append(all_marks, mark)
+			if marker != script_markers[0] && marker != script_markers[1] {
+				others = append(others, marker+": "+formatMark(mark))
+			}
+		}
+	}
+	row.OtherMarks = strings.Join(others, "; ")
+
+	switch {
+	case len(others) > 0:
+		// a third marker has looked at it, so go with the middle mark
+		sort.Float64s(all_marks)
+		row.Agreed = formatMark(all_marks[len(all_marks)/2])
+		row.Status = DoubleThirdMarked
+	case !has_first || !has_second:
+		row.Status = DoubleMissing
+	default:
+		difference := first - second
+		if difference < 0 {
+			difference = -difference
+		}
+		row.Difference = formatMark(difference)
+		if difference > tolerance+1e-9 {
+			row.Status = DoubleDisagree
+			break
+		}
+		row.Status = DoubleAgreed
+		if agree == AgreeHigher {
+			if second > first {
+				first = second
+			}
+			row.Agreed = formatMark(first)
+		} else {
+			row.Agreed = formatMark(roundToStep((first+second)/2, step))
+		}
+	}
+	return row
+}
+
+// WriteDoubleMarkingCSV saves the double marking report
+func WriteDoubleMarkingCSV(rows []DoubleMarkRow, outputCSV string) error {
+	file, err := os.OpenFile(outputCSV, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gocsv.MarshalFile(&rows, file)
+}
+
+// ParseAgree checks the method for proposing agreed marks
+func ParseAgree(str string) (string, error) {
+	switch str {
+	case AgreeMean, AgreeHigher:
+		return str, nil
+	}
+	return "", fmt.Errorf("unknown agreement method %q (use mean or higher)", str)
+}
@@ -0,0 +1,60 @@
+package pdfextract
+
+import (
+	"testing"
+)
+
+func TestReconcileDoubleMarking(t *testing.T) {
+
+	form_values := []FormValues{
+		markEntry("B000001", "GK", 1, 0, "3"),
+		markEntry("B000001", "GK", 1, 1, "6"),
+		markEntry("B000001", "GK", 2, 2, "7"),
+		markEntry("B000001", "JS", 1, 0, "3.5"),
+		markEntry("B000001", "JS", 1, 1, "5"),
+		markEntry("B000001", "JS", 2, 2, "4"),
+		markEntry("B000002", "GK", 1, 0, "4"),
+		markEntry("B000002", "JS", 1, 0, "4"),
+		markEntry("B000002", "JS", 1, 1, "2"),
+		markEntry("B000003", "GK", 1, 0, "1"),
+	}
+
+	rows := ReconcileDoubleMarking(form_values, testParts, DoubleMarkingOptions{PartTolerance: 1, TotalTolerance: 5, Agree: AgreeMean})
+
+	want := []DoubleMarkRow{
+		{ExamNumber: "B000001", Part: "1a", FirstMarker: "GK", FirstMark: "3", SecondMarker: "JS", SecondMark: "3.5", Difference: "0.5", Agreed: "3.5", Status: DoubleAgreed},
+		{ExamNumber: "B000001", Part: "1b", FirstMarker: "GK", FirstMark: "6", SecondMarker: "JS", SecondMark: "5", Difference: "1", Agreed: "6", Status: DoubleAgreed},
+		{ExamNumber: "B000001", Part: "2", FirstMarker: "GK", FirstMark: "7", SecondMarker: "JS", SecondMark: "4", Difference: "3", Status: DoubleDisagree},
+		{ExamNumber: "B000001", Part: "Total", FirstMarker: "GK", FirstMark: "16", SecondMarker: "JS", SecondMark: "12.5", Difference: "3.5", Status: DoubleDisagree},
+		{ExamNumber: "B000002", Part: "1a", FirstMarker: "GK", FirstMark: "4", SecondMarker: "JS", SecondMark: "4", Difference: "0", Agreed: "4", Status: DoubleAgreed},
+		{ExamNumber: "B000002", Part: "1b", FirstMarker: "GK", SecondMarker: "JS", SecondMark: "2", Status: DoubleMissing},
+		{ExamNumber: "B000002", Part: "Total", FirstMarker: "GK", FirstMark: "4", SecondMarker: "JS", SecondMark: "6", Difference: "2", Status: DoubleMissing},
+		{ExamNumber: "B000003", Part: "Total", FirstMarker: "GK", Status: DoubleSingleMarked},
+	}
+
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows: %+v", len(rows), rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d:\n got %+v\nwant %+v", i, rows[i], want[i])
+		}
+	}
+
+	rows = ReconcileDoubleMarking(form_values[:6], testParts, DoubleMarkingOptions{PartTolerance: 3, TotalTolerance: 5, Agree: AgreeHigher})
+	total := rows[len(rows)-1]
+	if total.Status != DoubleAgreed || total.Agreed != "16.5" {
+		t.Errorf("with higher marks, got total %+v", total)
+	}
+
+	// markers are in the order they marked the script, so a third marker never takes the place of the second
+	form_values = []FormValues{
+		markEntry("B000004", "JS", 1, 0, "3"),
+		markEntry("B000004", "GK", 2, 0, "4"),
+		markEntry("B000004", "AB", 3, 0, "2"),
+	}
+	rows = ReconcileDoubleMarking(form_values, testParts, DoubleMarkingOptions{PartTolerance: 1, TotalTolerance: 5, Agree: AgreeMean})
+	if row := rows[0]; row.FirstMarker != "JS" || row.SecondMarker != "GK" || row.OtherMarks != "AB: 2" || row.Agreed != "3" {
+		t.Errorf("with a third marker, got %+v", row)
+	}
+}
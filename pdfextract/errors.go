@@ -0,0 +1,50 @@
+package pdfextract
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/gocarina/gocsv"
+)
+
+// The stage of extraction at which a script ran into a problem
+const (
+	StageWalk       = "walk"        // the file could not be reached while looking through the folder
+	StageFilename   = "filename"    // the filename does not contain an exam number
+	StageOpen       = "open"        // the PDF could not be opened or parsed
+	StageHeader     = "header"      // the course code, exam number or marker could not be read from the first page
+	StageExamNumber = "exam number" // the exam number in the header does not match the filename
+	StageOutput     = "output"      // a new copy of the script could not be written
+)
+
+// ScriptError records a problem with one script, so that the run can carry on with the others
+type ScriptError struct {
+	File   string `csv:"File" json:"file"`
+	Stage  string `csv:"Stage" json:"stage"`
+	Reason string `csv:"Reason" json:"reason"`
+}
+
+func (e ScriptError) Error() string {
+	return fmt.Sprintf("%s (%s): %s", e.File, e.Stage, e.Reason)
+}
+
+// WriteScriptErrors saves the problems found with the scripts as a csv, and as json
+func WriteScriptErrors(script_errors []ScriptError, csv_path string, json_path string) error {
+
+	file, err := os.OpenFile(csv_path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := gocsv.MarshalFile(&script_errors, file); err != nil {
+		return err
+	}
+
+	json_data, err := json.MarshalIndent(script_errors, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(json_path, json_data, os.ModePerm)
+}
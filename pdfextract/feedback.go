@@ -0,0 +1,263 @@
+package pdfextract
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/timdrysdale/unipdf/v3/annotator"
+	"github.com/timdrysdale/unipdf/v3/contentstream"
+	"github.com/timdrysdale/unipdf/v3/core"
+	"github.com/timdrysdale/unipdf/v3/creator"
+	pdf "github.com/timdrysdale/unipdf/v3/model"
+)
+
+// Options for the feedback packs returned to students
+type FeedbackOptions struct {
+	Comments    bool         // include the markers' comments from the form
+	Conventions *Conventions // how to find the marker's initials in the header (default: DefaultConventions)
+}
+
+// isMarkerComment is true for the comment fields markers fill in, but not for the scan and heading check comments
+func isMarkerComment(field_name string) bool {
+	name := strings.ToLower(field_name)
+	if strings.HasPrefix(name, "scan-") || strings.HasPrefix(name, "heading-") {
+		return false
+	}
+	return strings.Contains(name, "comment")
+}
+
+// markerComments gives the non-empty comments on a script, in page order
+func markerComments(script *Script) []string {
+	type comment struct {
+		page int
+		text string
+	}
+	comments := []comment{}
+	for key, val := range script.Fields() {
+		page, field_name := whatPageIsThisFrom(key)
+		if page < 0 || !isMarkerComment(field_name) || !hasContent(val) {
+			continue
+		}
+		comments = append(comments, comment{page, strings.TrimSpace(val)})
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		if comments[i].page != comments[j].page {
+			return comments[i].page < comments[j].page
+		}
+		return comments[i].text < comments[j].text
+	})
+	lines := []string{}
+	for _, c := range comments {
+		lines = append(lines, fmt.Sprintf("Page %d: %s", c.page, c.text))
+	}
+	return lines
+}
+
+// initialsPattern matches any of the initials as a whole word, e.g. the AB in "Marker: AB", or is nil if there are none
+func initialsPattern(initials map[string]bool) *regexp.Regexp {
+	quoted := []string{}
+	for _, marker := range sortedKeys(initials) {
+		quoted = append(quoted, regexp.QuoteMeta(marker))
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`\b(?:` + strings.Join(quoted, "|") + `)\b`)
+}
+
+// blankInitials removes the initials from the text shown by a text showing operation, and is true if there were any.
+// Only operations showing one of the header lines are changed, so the same letters elsewhere on the page are left alone.
+func blankInitials(op *contentstream.ContentStreamOperation, pattern *regexp.Regexp, header map[string]bool) bool {
+	switch op.Operand {
+	case "Tj", "'", "\"":
+		if len(op.Params) == 0 {
+			return false
+		}
+		str, ok := op.Params[len(op.Params)-1].(*core.PdfObjectString)
+		if !ok || !header[strings.TrimSpace(str.Str())] || !pattern.MatchString(str.Str()) {
+			return false
+		}
+		op.Params[len(op.Params)-1] = core.MakeString(pattern.ReplaceAllString(str.Str(), ""))
+		return true
+	case "TJ":
+		if len(op.Params) != 1 {
+			return false
+		}
+		arr, ok := op.Params[0].(*core.PdfObjectArray)
+		if !ok {
+			return false
+		}
+		// the initials can be split up by kerning, so look at the text as a whole
+		text := ""
+		for _, obj := range arr.Elements() {
+			if str, ok := obj.(*core.PdfObjectString); ok {
+				text += str.Str()
+			}
+		}
+		if !header[strings.TrimSpace(text)] || !pattern.MatchString(text) {
+			return false
+		}
+		op.Params = []core.PdfObject{core.MakeArray(core.MakeString(pattern.ReplaceAllString(text, "")))}
+		return true
+	}
+	return false
+}
+
+// stripInitials blanks the initials in the header lines shown on the page, leaving the rest of the page as it was.
+// This matches the raw strings in the content stream, which works for the simply encoded fonts used in the headers.
+func stripInitials(page *pdf.PdfPage, pattern *regexp.Regexp, header map[string]bool) error {
+	if pattern == nil || len(header) == 0 {
+		return nil
+	}
+	cstream, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+	ops, err := contentstream.NewContentStreamParser(cstream).Parse()
+	if err != nil {
+		return err
+	}
+	stripped := 0
+	for _, op := range *ops {
+		if blankInitials(op, pattern, header) {
+			stripped++
+		}
+	}
+	if stripped == 0 {
+		return nil
+	}
+	return page.SetContentStreams([]string{string(ops.Bytes())}, core.NewFlateEncoder())
+}
+
+// headerWithInitials gives the lines of a page's header that show any of the initials
+func headerWithInitials(header string, pattern *regexp.Regexp) map[string]bool {
+	lines := make(map[string]bool)
+	for _, line := range strings.Split(header, "\n") {
+		if pattern.MatchString(line) {
+			lines[strings.TrimSpace(line)] = true
+		}
+	}
+	return lines
+}
+
+// WriteFeedback saves a feedback pack for one student: a page with the marks for each part and the total (and the
+// markers' comments if opts.Comments is set), followed by each marked copy of the script. The form fields are
+// flattened, so their names (which include the marker's initials) are dropped, and the initials are removed from the
+// header of each page - both those of the known markers, and any that the header patterns find there.
+func WriteFeedback(script_paths []string, summary *MarksSummary, script ScriptSummary, opts FeedbackOptions, output_path string) error {
+
+	if len(script_paths) == 0 {
+		return errors.New("no PDFs found for " + script.ExamNumber)
+	}
+	conv := opts.Conventions
+	if conv == nil {
+		conv = DefaultConventions()
+	}
+
+	initials := make(map[string]bool)
+	for _, marker := range summary.Markers {
+		if marker != "" {
+			initials[marker] = true
+		}
+	}
+
+	scripts := []*Script{}
+	defer func() {
+		for _, s := range scripts {
+			s.Close()
+		}
+	}()
+	comments := []string{}
+	for _, path := range script_paths {
+		s, err := OpenScript(path)
+		if err != nil {
+			return err
+		}
+		scripts = append(scripts, s)
+		if opts.Comments {
+			comments = append(comments, markerComments(s)...)
+		}
+		if err := s.reader.FlattenFields(true, annotator.FieldAppearance{OnlyIfMissing: true}); err != nil {
+			return fmt.Errorf("%s: flattening the form: %v", path, err)
+		}
+		// the initials are only looked for in the header at the top of each page, as ReadFormFromPDF finds them, so
+		// that the same letters in the student's work are left alone
+		text := s.Text()
+		header_lines := conv.headerLines(text)
+		header := make(map[int]string)
+		for p := range text {
+			header[p] = topLines(text[p], header_lines)
+			if marker := conv.extractMarkerInitials(map[int]string{0: header[p]}); marker != "" {
+				initials[marker] = true
+			}
+		}
+		pattern := initialsPattern(initials)
+		if pattern == nil {
+			continue
+		}
+		in_body := make(map[int]int) // in_body[page] = 2 - the times the initials' letters appear outside the header
+		for p, page := range s.reader.PageList {
+			in_body[p] = len(pattern.FindAllString(text[p], -1)) - len(pattern.FindAllString(header[p], -1))
+			if err := stripInitials(page, pattern, headerWithInitials(header[p], pattern)); err != nil {
+				return fmt.Errorf("%s: page %d: %v", path, p+1, err)
+			}
+		}
+		// never hand back a script that still shows who marked it
+		for p, stripped := range s.Text() {
+			if len(pattern.FindAllString(stripped, -1)) > in_body[p] {
+				return fmt.Errorf("%s: could not remove the marker's initials from page %d", path, p+1)
+			}
+		}
+	}
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSizeA4)
+	c.NewPage()
+
+	if err := coverText(c, "Feedback", 18, true); err != nil {
+		return err
+	}
+	if err := coverText(c, fmt.Sprintf("%s   %s", summary.Course, script.ExamNumber), 14, true); err != nil {
+		return err
+	}
+	if err := marksTable(c, summary, script, false); err != nil {
+		return err
+	}
+	if len(comments) > 0 {
+		if err := coverText(c, "Comments", 12, true); err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			if err := coverText(c, comment, 10, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, s := range scripts {
+		for _, page := range s.reader.PageList {
+			if err := c.AddPage(page); err != nil {
+				return err
+			}
+		}
+	}
+	return c.WriteToFile(output_path)
+}
+
+// MatriculationNumbers gives the matriculation number for each exam number in a Learn ingest report
+func MatriculationNumbers(ingestCSV string) (map[string]string, error) {
+	subs, err := readIngestReport(ingestCSV)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ingestCSV, err)
+	}
+	matric := make(map[string]string)
+	for _, sub := range subs {
+		if sub.ExamNumber != "" && sub.Matriculation != "" {
+			matric[sub.ExamNumber] = sub.Matriculation
+		}
+	}
+	return matric, nil
+}
@@ -0,0 +1,147 @@
+package pdfextract
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timdrysdale/unipdf/v3/contentstream"
+	"github.com/timdrysdale/unipdf/v3/core"
+	pdf "github.com/timdrysdale/unipdf/v3/model"
+)
+
+func TestIsMarkerComment(t *testing.T) {
+	for name, want := range map[string]bool{
+		"comment":           true,
+		"qn-part-comment-2": true,
+		"Comments":          true,
+		"scan-comment-1":    false,
+		"heading-comment-2": false,
+		"qn-part-mark-2":    false,
+	} {
+		if got := isMarkerComment(name); got != want {
+			t.Errorf("isMarkerComment(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestBlankInitials(t *testing.T) {
+	pattern := initialsPattern(map[string]bool{"AB": true})
+	header := map[string]bool{"AB": true, "Marker: AB": true}
+	tests := []struct {
+		op   *contentstream.ContentStreamOperation
+		want bool
+		text string
+	}{
+		{&contentstream.ContentStreamOperation{Operand: "Tj", Params: []core.PdfObject{core.MakeString("AB")}}, true, ""},
+		{&contentstream.ContentStreamOperation{Operand: "Tj", Params: []core.PdfObject{core.MakeString("Marker: AB")}}, true, "Marker: "},
+		{&contentstream.ContentStreamOperation{Operand: "TJ", Params: []core.PdfObject{core.MakeArray(core.MakeString("A"), core.MakeString("B"))}}, true, ""},
+		{&contentstream.ContentStreamOperation{Operand: "Tj", Params: []core.PdfObject{core.MakeString("MATH01234 B123456")}}, false, "MATH01234 B123456"},
+		{&contentstream.ContentStreamOperation{Operand: "Tj", Params: []core.PdfObject{core.MakeString("ABC")}}, false, "ABC"},
+		{&contentstream.ContentStreamOperation{Operand: "Tf", Params: []core.PdfObject{core.MakeString("AB")}}, false, "AB"},
+		{&contentstream.ContentStreamOperation{Operand: "Tj", Params: []core.PdfObject{core.MakeString("Let AB be a chord")}}, false, "Let AB be a chord"},
+	}
+	for i, test := range tests {
+		if got := blankInitials(test.op, pattern, header); got != test.want {
+			t.Errorf("test %d: blankInitials = %v, want %v", i, got, test.want)
+		}
+		text := ""
+		switch param := test.op.Params[len(test.op.Params)-1].(type) {
+		case *core.PdfObjectString:
+			text = param.Str()
+		case *core.PdfObjectArray:
+			for _, obj := range param.Elements() {
+				text += obj.(*core.PdfObjectString).Str()
+			}
+		}
+		if text != test.text {
+			t.Errorf("test %d: left %q, want %q", i, text, test.text)
+		}
+	}
+}
+
+func TestStripInitials(t *testing.T) {
+
+	// a header like the one on each page of a marked script, with the initials after a label
+	conv, err := CourseConfig{HeaderPatterns: []string{
+		"(?P<course>[a-zA-Z0-9]+) ",
+		" (?P<exam>[a-zA-Z0-9]+)\n",
+		"Marker: (?P<marker>[A-Z]+)",
+	}}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := map[int]string{0: "MATH01234 B123456\nMarker: AB\nLet AB be a chord\n"}
+	lines := conv.headerLines(text)
+	if lines != 2 {
+		t.Fatalf("header has %d lines", lines)
+	}
+	header := topLines(text[0], lines)
+	if marker := conv.extractMarkerInitials(map[int]string{0: header}); marker != "AB" {
+		t.Fatalf("found marker %q", marker)
+	}
+	pattern := initialsPattern(map[string]bool{"AB": true})
+
+	// the student has used the same letters in their answer, which must be left alone
+	page := pdf.NewPdfPage()
+	content := "BT\n/F1 10 Tf\n72 800 Td\n(MATH01234 B123456) Tj\n0 -12 Td\n(Marker: AB) Tj\n0 -12 Td\n(Let AB be a chord) Tj\n0 -12 Td\n(ABC) Tj\nET\n"
+	if err := page.SetContentStreams([]string{content}, core.NewRawEncoder()); err != nil {
+		t.Fatal(err)
+	}
+	if err := stripInitials(page, pattern, headerWithInitials(header, pattern)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := page.GetAllContentStreams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"(MATH01234 B123456) Tj", "(Marker: ) Tj", "(Let AB be a chord) Tj", "(ABC) Tj"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q from the stripped page:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Marker: AB") {
+		t.Errorf("initials left on the stripped page:\n%s", got)
+	}
+}
+
+func TestHeaderInitials(t *testing.T) {
+
+	// with the default patterns the initials are the second line, so an answer that is a single word is not
+	// mistaken for them
+	conv := DefaultConventions()
+	text := map[int]string{0: "MATH01234 B123456\nAB\nLet AB be a chord\n", 1: "MATH01234 B123456\n\nProof\nQED\n"}
+	lines := conv.headerLines(text)
+	if lines != 2 {
+		t.Fatalf("header has %d lines", lines)
+	}
+	for p, want := range map[int]string{0: "AB", 1: ""} {
+		if got := conv.extractMarkerInitials(map[int]string{0: topLines(text[p], lines)}); got != want {
+			t.Errorf("page %d: found marker %q, want %q", p+1, got, want)
+		}
+	}
+	if got := topLines("one\ntwo", 3); got != "one\ntwo" {
+		t.Errorf("topLines = %q", got)
+	}
+}
+
+func TestMatriculationNumbers(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gradex-feedback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ingest.csv")
+	ioutil.WriteFile(path, []byte("FirstName,LastName,Matriculation,ExamNumber\nAnn,Smith,s1234567,B123456\nBen,Jones,,B654321\n"), 0644)
+	matric, err := MatriculationNumbers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matric) != 1 || matric["B123456"] != "s1234567" {
+		t.Errorf("got %v", matric)
+	}
+}
@@ -0,0 +1,122 @@
+package pdfextract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// Grade boundaries, read from a json file giving the lowest percentage for each grade, e.g.
+//
+//	{
+//		"boundaries": [
+//			{"grade": "A1", "from": 90},
+//			{"grade": "A2", "from": 80},
+//			{"grade": "A3", "from": 70},
+//			{"grade": "B", "from": 60},
+//			{"grade": "C", "from": 50},
+//			{"grade": "D", "from": 40},
+//			{"grade": "F", "from": 0}
+//		]
+//	}
+type GradeBoundary struct {
+	Grade string  `json:"grade"`
+	From  float64 `json:"from"` // lowest percentage for this grade
+}
+
+// GradeBoundaries are kept in order from the highest grade to the lowest
+type GradeBoundaries []GradeBoundary
+
+// The number of scripts given each grade
+type GradeCount struct {
+	Grade string  `json:"grade"`
+	From  float64 `json:"from"`
+	Count int     `json:"count"`
+}
+
+// LoadGradeBoundaries reads and checks a grade boundaries file
+func LoadGradeBoundaries(path string) (GradeBoundaries, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := struct {
+		Boundaries GradeBoundaries `json:"boundaries"`
+	}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := config.Boundaries.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return config.Boundaries, nil
+}
+
+// check makes sure the boundaries make sense, and puts them in order
+func (boundaries GradeBoundaries) check() error {
+	if len(boundaries) == 0 {
+		return errors.New("no grade boundaries")
+	}
+	grades := make(map[string]bool)
+	for _, boundary := range boundaries {
+		if boundary.Grade == "" {
+			return errors.New("a grade boundary has no grade")
+		}
+		if grades[boundary.Grade] {
+			return fmt.Errorf("grade %s appears more than once", boundary.Grade)
+		}
+		if boundary.From < 0 || boundary.From > 100 {
+			return fmt.Errorf("grade %s starts at %v%%, which is not a percentage", boundary.Grade, boundary.From)
+		}
+		grades[boundary.Grade] = true
+	}
+	sort.SliceStable(boundaries, func(i, j int) bool { return boundaries[i].From > boundaries[j].From })
+	for i := 1; i < len(boundaries); i++ {
+		if boundaries[i].From == boundaries[i-1].From {
+			return fmt.Errorf("grades %s and %s both start at %v%%", boundaries[i-1].Grade, boundaries[i].Grade, boundaries[i].From)
+		}
+	}
+	return nil
+}
+
+// Grade gives the grade for a percentage, or "" if it is below the lowest boundary
+func (boundaries GradeBoundaries) Grade(percentage float64) string {
+	for _, boundary := range boundaries {
+		if percentage >= boundary.From-1e-9 {
+			return boundary.Grade
+		}
+	}
+	return ""
+}
+
+// applyGrades works out the percentage and grade of each marked script (from the scaled total, if the marks have been
+// scaled), and how many scripts got each grade
+func applyGrades(summary *MarksSummary, boundaries GradeBoundaries) {
+	if len(boundaries) == 0 || summary.OutOf <= 0 {
+		return
+	}
+	summary.Grades = make([]GradeCount, 0, len(boundaries))
+	index := make(map[string]int)
+	for i, boundary := range boundaries {
+		summary.Grades = append(summary.Grades, GradeCount{Grade: boundary.Grade, From: boundary.From})
+		index[boundary.Grade] = i
+	}
+	for i := range summary.Scripts {
+		script := &summary.Scripts[i]
+		if script.Status == StatusUnmarked {
+			continue
+		}
+		total := script.Total
+		if script.ScaledTotal != nil {
+			total = *script.ScaledTotal
+		}
+		percentage := 100 * total / summary.OutOf
+		script.Percentage = &percentage
+		script.Grade = boundaries.Grade(percentage)
+		if i, ok := index[script.Grade]; ok {
+			summary.Grades[i].Count++
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package pdfextract
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadGradeBoundaries(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gradex-grades")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "grades.json")
+	ioutil.WriteFile(path, []byte(`{"boundaries": [{"grade": "F", "from": 0}, {"grade": "A", "from": 70}, {"grade": "B", "from": 60}, {"grade": "C", "from": 40}]}`), 0644)
+	boundaries, err := LoadGradeBoundaries(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if boundaries[0].Grade != "A" || boundaries[3].Grade != "F" {
+		t.Errorf("boundaries not in order: %+v", boundaries)
+	}
+	for percentage, grade := range map[float64]string{100: "A", 70: "A", 69.9: "B", 40: "C", 12: "F", 0: "F"} {
+		if got := boundaries.Grade(percentage); got != grade {
+			t.Errorf("Grade(%v) = %q, want %q", percentage, got, grade)
+		}
+	}
+
+	for _, bad := range []string{
+		`{"boundaries": []}`,
+		`{"boundaries": [{"grade": "A", "from": 70}, {"grade": "A", "from": 60}]}`,
+		`{"boundaries": [{"grade": "A", "from": 170}]}`,
+		`{"boundaries": [{"grade": "A", "from": 50}, {"grade": "B", "from": 50}]}`,
+	} {
+		ioutil.WriteFile(path, []byte(bad), 0644)
+		if _, err := LoadGradeBoundaries(path); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
+
+func TestSummaryGrades(t *testing.T) {
+
+	form_values := []FormValues{
+		markEntry("B000001", "GK", 1, 0, "4"),
+		markEntry("B000001", "GK", 1, 1, "6"),
+		markEntry("B000001", "GK", 2, 2, "5"),
+		markEntry("B000002", "GK", 1, 0, "1"),
+		markEntry("B000002", "GK", 1, 1, "2"),
+		markEntry("B000002", "GK", 2, 2, "3"),
+		blankEntry("B000003", 1),
+	}
+	grades := GradeBoundaries{{"A", 70}, {"C", 40}, {"F", 0}}
+
+	summary := SummariseMarking(form_values, testParts, ValidationOptions{Grades: grades})
+
+	first, second, unmarked := summary.Scripts[0], summary.Scripts[1], summary.Scripts[2]
+	if *first.Percentage != 75 || first.Grade != "A" || summary.Cell(first, "Percentage") != "75.0" {
+		t.Errorf("B000001: %v%%, %q", *first.Percentage, first.Grade)
+	}
+	if second.Grade != "F" || unmarked.Grade != "" || unmarked.Percentage != nil {
+		t.Errorf("got grades %q and %q", second.Grade, unmarked.Grade)
+	}
+	want := []GradeCount{{"A", 70, 1}, {"C", 40, 0}, {"F", 0, 1}}
+	if !reflect.DeepEqual(summary.Grades, want) || !reflect.DeepEqual(summary.TotalColumns(), []string{"Total", "Percentage", "Grade"}) {
+		t.Errorf("got grade distribution %+v", summary.Grades)
+	}
+}
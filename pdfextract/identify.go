@@ -0,0 +1,137 @@
+package pdfextract
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/timdrysdale/parselearn"
+)
+
+// The marks joined with the students' details from the Learn ingest report, by exam number
+type IdentifiedMarks struct {
+	Summary     *MarksSummary
+	Submissions map[string]*parselearn.Submission // keyed by exam number
+	Unmatched   []string                          // exam numbers with marks but no submission in the ingest report
+	NotMarked   []string                          // exam numbers in the ingest report with no marked script
+	Duplicates  []string                          // exam numbers that appear more than once in the ingest report
+}
+
+func normaliseExamNumber(examno string) string {
+	return strings.ToUpper(strings.TrimSpace(examno))
+}
+
+// IdentifyMarks joins the summarised marks with the submissions in the ingest report at ingestCSV
+func IdentifyMarks(summary *MarksSummary, ingestCSV string) (*IdentifiedMarks, error) {
+
+	subs, err := readIngestReport(ingestCSV)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ingestCSV, err)
+	}
+
+	identified := &IdentifiedMarks{Summary: summary, Submissions: make(map[string]*parselearn.Submission)}
+	duplicates := make(map[string]bool)
+	for _, sub := range subs {
+		examno := normaliseExamNumber(sub.ExamNumber)
+		if examno == "" {
+			continue
+		}
+		if _, seen := identified.Submissions[examno]; seen {
+			duplicates[examno] = true
+			continue
+		}
+		identified.Submissions[examno] = sub
+	}
+	// don't guess which student a duplicated exam number belongs to
+	for examno := range duplicates {
+		delete(identified.Submissions, examno)
+	}
+	identified.Duplicates = sortedKeys(duplicates)
+
+	marked := make(map[string]bool)
+	for _, script := range summary.Scripts {
+		examno := normaliseExamNumber(script.ExamNumber)
+		marked[examno] = true
+		if _, ok := identified.Submissions[examno]; !ok && !duplicates[examno] {
+			identified.Unmatched = append(identified.Unmatched, script.ExamNumber)
+		}
+	}
+	for examno := range identified.Submissions {
+		if !marked[examno] {
+			identified.NotMarked = append(identified.NotMarked, examno)
+		}
+	}
+	sort.Strings(identified.NotMarked)
+
+	return identified, nil
+}
+
+// WriteIdentifiedMarksCSV saves the final marks sheet, with each student's details before their marks for each part
+// and subtotal (as numbers, rather than the values entered) and their total.
+// Scripts with no submission (or more than one) in the ingest report are kept, with the details left blank.
+func WriteIdentifiedMarksCSV(identified *IdentifiedMarks, outputCSV string) error {
+
+	file, err := os.OpenFile(outputCSV, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+
+	summary := identified.Summary
+	header := []string{"Matriculation", "Last Name", "First Name", "Exam Number", "Assignment", "Date Submitted", "Original Filename"}
+	for _, column := range summary.Columns() {
+		header = append(header, column.Name)
+	}
+	header = append(header, summary.TotalColumns()...)
+	w.Write(header)
+
+	for _, script := range summary.Scripts {
+		sub, ok := identified.Submissions[normaliseExamNumber(script.ExamNumber)]
+		if !ok {
+			sub = &parselearn.Submission{}
+		}
+		row := []string{sub.Matriculation, sub.LastName, sub.FirstName, script.ExamNumber, sub.Assignment, sub.DateSubmitted, sub.OriginalFilename}
+		for _, column := range summary.Columns() {
+			mark := ""
+			if m, ok := script.Marks[column.Name]; ok {
+				mark = formatMark(m)
+			}
+			row = append(row, mark)
+		}
+		for _, column := range summary.TotalColumns() {
+			row = append(row, summary.Cell(script, column))
+		}
+		w.Write(row)
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// WriteUnmatchedCSV lists the exam numbers that could not be joined with the ingest report, and why
+func WriteUnmatchedCSV(identified *IdentifiedMarks, outputCSV string) error {
+
+	file, err := os.OpenFile(outputCSV, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+
+	w.Write([]string{"Exam Number", "Problem"})
+	for _, examno := range identified.Unmatched {
+		w.Write([]string{examno, "marked, but not in the ingest report"})
+	}
+	for _, examno := range identified.NotMarked {
+		w.Write([]string{examno, "in the ingest report, but no marked script"})
+	}
+	for _, examno := range identified.Duplicates {
+		w.Write([]string{examno, "more than one submission in the ingest report"})
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,123 @@
+package pdfextract
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIdentifyMarks(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gradex-identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ingest := filepath.Join(dir, "ingest.csv")
+	ioutil.WriteFile(ingest, []byte("FirstName,LastName,Matriculation,ExamNumber,DateSubmitted\n"+
+		"Ann,Smith,s1111111,b100001,2021-05-20\n"+
+		"Ben,Jones,s2222222,B100002,2021-05-20\n"+
+		"Cat,Brown,s3333333,B100003,2021-05-21\n"+
+		"Dan,Green,s4444444,B100003,2021-05-21\n"+
+		"Eve,White,s5555555,B100005,2021-05-21\n"), 0644)
+
+	summary := &MarksSummary{
+		Parts: []ColumnSummary{{Name: "1", OutOf: 10}},
+		Scripts: []ScriptSummary{
+			{ExamNumber: "B100001", Marks: map[string]float64{"1": 7}, Total: 7},
+			{ExamNumber: "B100003", Marks: map[string]float64{"1": 5}, Total: 5},
+			{ExamNumber: "B100004", Marks: map[string]float64{"1": 9}, Total: 9},
+			{ExamNumber: "B100005", Marks: map[string]float64{"1": 0}, Total: 0},
+		},
+	}
+	identified, err := IdentifyMarks(summary, ingest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(identified.Unmatched, []string{"B100004"}) {
+		t.Errorf("unmatched %v", identified.Unmatched)
+	}
+	if !reflect.DeepEqual(identified.NotMarked, []string{"B100002"}) {
+		t.Errorf("not marked %v", identified.NotMarked)
+	}
+	if !reflect.DeepEqual(identified.Duplicates, []string{"B100003"}) {
+		t.Errorf("duplicates %v", identified.Duplicates)
+	}
+
+	outputCSV := filepath.Join(dir, "identified.csv")
+	if err := WriteIdentifiedMarksCSV(identified, outputCSV); err != nil {
+		t.Fatal(err)
+	}
+	file, _ := os.Open(outputCSV)
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Matriculation", "Last Name", "First Name", "Exam Number", "Assignment", "Date Submitted", "Original Filename", "1", "Total"},
+		{"s1111111", "Smith", "Ann", "B100001", "", "2021-05-20", "", "7", "7"},
+		{"", "", "", "B100003", "", "", "", "5", "5"},
+		{"", "", "", "B100004", "", "", "", "9", "9"},
+		{"s5555555", "White", "Eve", "B100005", "", "2021-05-21", "", "0", "0"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got\n%v\nwant\n%v", rows, want)
+	}
+}
+
+func TestIdentifyOverAnswered(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gradex-identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ingest := filepath.Join(dir, "ingest.csv")
+	ioutil.WriteFile(ingest, []byte("FirstName,LastName,Matriculation,ExamNumber,DateSubmitted\n"+
+		"Ann,Smith,s1111111,B100001,2021-05-20\n"+
+		"Ben,Jones,s2222222,B100002,2021-05-20\n"), 0644)
+
+	parts := []*PaperStructure{
+		{Part: "1", Marks: 10},
+		{Part: "2", Marks: 10, Choice: "B", Choose: 2},
+		{Part: "3", Marks: 10, Choice: "B", Choose: 2},
+		{Part: "4", Marks: 10, Choice: "B", Choose: 2},
+	}
+	form_values := []FormValues{
+		markEntry("B100001", "GK", 1, 0, "5"),
+		markEntry("B100001", "GK", 1, 1, "6"),
+		markEntry("B100001", "GK", 1, 2, "7"),
+		markEntry("B100001", "GK", 1, 3, "2"),
+		markEntry("B100002", "GK", 1, 0, "8"),
+		markEntry("B100002", "GK", 1, 1, "4"),
+		markEntry("B100002", "GK", 1, 2, "3"),
+	}
+	summary := SummariseMarking(form_values, parts, ValidationOptions{})
+
+	// answering too many questions is noted, but the marking is still complete so the marks can be identified
+	over := summary.Scripts[0]
+	want_warnings := map[string]string{"choice B": "answered 3 questions, best 2 counted (Q4 dropped)"}
+	if over.Status != StatusComplete || over.Total != 18 || len(over.Validation) > 0 || !reflect.DeepEqual(over.Warnings, want_warnings) {
+		t.Errorf("B100001: %+v", over)
+	}
+	if got := over.ValidationString(); got != "choice B: answered 3 questions, best 2 counted (Q4 dropped)" {
+		t.Errorf("ValidationString() = %q", got)
+	}
+	if summary.Statistics.Complete != len(summary.Scripts) {
+		t.Errorf("%d of %d scripts complete", summary.Statistics.Complete, len(summary.Scripts))
+	}
+
+	identified, err := IdentifyMarks(summary, ingest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identified.Unmatched)+len(identified.NotMarked)+len(identified.Duplicates) > 0 {
+		t.Errorf("got %+v", identified)
+	}
+}
@@ -0,0 +1,127 @@
+package pdfextract
+
+import (
+	"encoding/csv"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// ItemAnalysis is the classical item analysis of the paper, for reviewing the quality of each part
+type ItemAnalysis struct {
+	Scripts int              `json:"scripts"` // number of marked scripts
+	Items   []ItemStatistics `json:"items"`
+	Alpha   *float64         `json:"alpha"` // Cronbach's alpha over the compulsory parts
+}
+
+// The item statistics for one part. Each is nil when it cannot be worked out, e.g. with too few scripts.
+type ItemStatistics struct {
+	Part                 string   `json:"part"`
+	OutOf                float64  `json:"out_of"`
+	Count                int      `json:"count"`                  // scripts with a mark for this part
+	Facility             *float64 `json:"facility"`               // mean mark / marks available
+	Discrimination       *float64 `json:"discrimination"`         // (mean of the top third - mean of the bottom third, by total) / marks available
+	PartTotalCorrelation *float64 `json:"part_total_correlation"` // correlation of the part with the rest of the total
+}
+
+// AnalyseItems works out the item statistics for each part from the marks in the summary. Only the marked scripts
+// are used, and optional parts only use the scripts that answered them, so they are left out of Cronbach's alpha.
+func AnalyseItems(summary *MarksSummary) *ItemAnalysis {
+
+	scripts := []ScriptSummary{}
+	for _, script := range summary.Scripts {
+		if script.Status != StatusUnmarked {
+			scripts = append(scripts, script)
+		}
+	}
+	// the top and bottom thirds are found from the totals
+	sort.SliceStable(scripts, func(i, j int) bool { return scripts[i].Total > scripts[j].Total })
+
+	analysis := &ItemAnalysis{Scripts: len(scripts), Items: []ItemStatistics{}}
+	compulsory := []ColumnSummary{}
+	for _, part := range summary.Parts {
+		item := ItemStatistics{Part: part.Name, OutOf: part.OutOf}
+		if !part.Optional {
+			compulsory = append(compulsory, part)
+		}
+
+		marks := []float64{}
+		rest := []float64{} // the total without this part - a part that was dropped from the total added nothing to it
+		for _, script := range scripts {
+			if part.Optional && len(script.MarksEntered[part.Name]) == 0 {
+				continue
+			}
+			marks = append(marks, script.Marks[part.Name])
+			if contains(script.DroppedParts, part.Name) {
+				rest = append(rest, script.Total)
+			} else {
+				rest = append(rest, script.Total-script.Marks[part.Name])
+			}
+		}
+		item.Count = len(marks)
+
+		if item.Count > 0 && part.OutOf > 0 {
+			facility := mean(marks) / part.OutOf
+			item.Facility = &facility
+		}
+		if third := item.Count / 3; third > 0 && part.OutOf > 0 {
+			// marks are in order of total, highest first
+			discrimination := (mean(marks[:third]) - mean(marks[item.Count-third:])) / part.OutOf
+			item.Discrimination = &discrimination
+		}
+		if r, ok := correlation(marks, rest); ok {
+			item.PartTotalCorrelation = &r
+		}
+		analysis.Items = append(analysis.Items, item)
+	}
+
+	// Cronbach's alpha = k/(k-1) * (1 - sum of the part variances / variance of the totals)
+	k := len(compulsory)
+	if k > 1 && len(scripts) > 1 {
+		totals := make([]float64, len(scripts))
+		sum_of_variances := 0.0
+		for _, part := range compulsory {
+			marks := make([]float64, len(scripts))
+			for i, script := range scripts {
+				marks[i] = script.Marks[part.Name]
+				totals[i] = totals[i] + marks[i]
+			}
+			sd := standardDeviation(marks)
+			sum_of_variances = sum_of_variances + sd*sd
+		}
+		if sd := standardDeviation(totals); sd > 0 {
+			alpha := float64(k) / float64(k-1) * (1 - sum_of_variances/(sd*sd))
+			analysis.Alpha = &alpha
+		}
+	}
+	return analysis
+}
+
+// WriteItemAnalysisCSV saves the item analysis, with Cronbach's alpha at the end
+func WriteItemAnalysisCSV(analysis *ItemAnalysis, outputCSV string) error {
+
+	file, err := os.OpenFile(outputCSV, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+
+	stat := func(x *float64) string {
+		if x == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*x, 'f', 3, 64)
+	}
+
+	w.Write([]string{"Part", "Out of", "Scripts", "Facility", "Discrimination", "Part-Total Correlation"})
+	for _, item := range analysis.Items {
+		w.Write([]string{item.Part, formatMark(item.OutOf), strconv.Itoa(item.Count), stat(item.Facility), stat(item.Discrimination), stat(item.PartTotalCorrelation)})
+	}
+	w.Write([]string{""})
+	w.Write([]string{"Scripts:", strconv.Itoa(analysis.Scripts)})
+	w.Write([]string{"Cronbach's alpha:", stat(analysis.Alpha)})
+
+	w.Flush()
+	return w.Error()
+}
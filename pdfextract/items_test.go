@@ -0,0 +1,72 @@
+package pdfextract
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// stat shows a statistic that may not have been worked out
+func showStat(x *float64) string {
+	if x == nil {
+		return "nil"
+	}
+	return fmt.Sprint(*x)
+}
+
+func TestAnalyseItems(t *testing.T) {
+
+	script := func(examno string, a, b, c float64) ScriptSummary {
+		return ScriptSummary{ExamNumber: examno, Status: StatusComplete,
+			Marks: map[string]float64{"A": a, "B": b, "C": c}, Total: a + b + c}
+	}
+	summary := &MarksSummary{
+		Parts: []ColumnSummary{{Name: "A", OutOf: 10}, {Name: "B", OutOf: 10}, {Name: "C", OutOf: 10}},
+		Scripts: []ScriptSummary{
+			script("B000001", 3, 4, 2),
+			script("B000002", 9, 8, 7),
+			script("B000003", 6, 5, 6),
+			{ExamNumber: "B000004", Status: StatusUnmarked},
+		},
+	}
+
+	analysis := AnalyseItems(summary)
+
+	if analysis.Scripts != 3 || len(analysis.Items) != 3 {
+		t.Fatalf("got %+v", analysis)
+	}
+	a := analysis.Items[0]
+	if a.Count != 3 || a.Facility == nil || *a.Facility != 0.6 || a.Discrimination == nil || math.Abs(*a.Discrimination-0.6) > 1e-9 ||
+		a.PartTotalCorrelation == nil || *a.PartTotalCorrelation < 0.9 {
+		t.Errorf("got A: count %d, facility %v, discrimination %v, correlation %v", a.Count, showStat(a.Facility), showStat(a.Discrimination), showStat(a.PartTotalCorrelation))
+	}
+	if analysis.Alpha == nil || math.Abs(*analysis.Alpha-0.9586) > 0.0001 {
+		t.Errorf("got alpha %v", showStat(analysis.Alpha))
+	}
+
+	// too few scripts for the statistics that need a spread of marks
+	summary.Scripts = summary.Scripts[:1]
+	analysis = AnalyseItems(summary)
+	if analysis.Items[0].Discrimination != nil || analysis.Items[0].PartTotalCorrelation != nil || analysis.Alpha != nil {
+		t.Errorf("with one script: %+v", analysis.Items[0])
+	}
+
+	// a dropped optional part never added to the total, so the rest of the total is the whole total
+	optional := func(examno string, a, b, total float64, dropped string) ScriptSummary {
+		return ScriptSummary{ExamNumber: examno, Status: StatusComplete, MarksEntered: map[string][]string{"A": {"x"}, "B": {"x"}},
+			Marks: map[string]float64{"A": a, "B": b}, Total: total, DroppedParts: []string{dropped}}
+	}
+	summary = &MarksSummary{
+		Parts: []ColumnSummary{{Name: "A", OutOf: 10, Optional: true}, {Name: "B", OutOf: 10, Optional: true}},
+		Scripts: []ScriptSummary{
+			optional("B000001", 2, 6, 6, "A"),
+			optional("B000002", 4, 8, 8, "A"),
+			optional("B000003", 9, 1, 9, "B"),
+		},
+	}
+	analysis = AnalyseItems(summary)
+	// A's rest totals are 6, 8 and 0 - taking A's marks off the totals would give 4, 4 and 0, and a correlation of -0.96
+	if r := analysis.Items[0].PartTotalCorrelation; r == nil || math.Abs(*r+0.8660) > 0.0001 {
+		t.Errorf("got correlation %v for the optional part A", showStat(r))
+	}
+}
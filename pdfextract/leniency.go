@@ -0,0 +1,159 @@
+package pdfextract
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/gocarina/gocsv"
+)
+
+// How a marker's marks compare with the other markers' marks for the same part
+const (
+	LeniencyLenient = "lenient"
+	LeniencySevere  = "severe"
+)
+
+// MarkerComparison sets out one marker's marks for one part alongside the marks from the other markers
+type MarkerComparison struct {
+	Part        string  `json:"part"`
+	Marker      string  `json:"marker"`
+	Count       int     `json:"count"`
+	Mean        float64 `json:"mean"`
+	Median      float64 `json:"median"`
+	SD          float64 `json:"sd"`
+	OthersCount int     `json:"others_count"`
+	OthersMean  float64 `json:"others_mean"`
+	OthersSD    float64 `json:"others_sd"`
+	EffectSize  float64 `json:"effect_size"`    // (Mean - OthersMean) / OthersSD
+	Z           float64 `json:"z"`              // (Mean - OthersMean) / sqrt(SD²/Count + OthersSD²/OthersCount)
+	Flag        string  `json:"flag,omitempty"` // lenient or severe, when |Z| is over the threshold
+}
+
+// CompareMarkers works out the statistics of each marker's original marks (before moderation) for each part that was
+// shared between several markers, and compares them with the marks from the other markers on that part - leaving out
+// the marker's own marks, which would otherwise pull the comparison towards them. A marker is flagged as lenient or
+// severe on a part when the difference between their mean and the others' mean is more than z_threshold standard
+// errors.
+func CompareMarkers(form_values []FormValues, parts []*PaperStructure, z_threshold float64) []MarkerComparison {
+
+	part_name := make(map[int]string)
+	for pnum, part := range parts {
+		if part.Part != "" {
+			part_name[pnum] = part.Label()
+		}
+	}
+
+	// each marker's mark for each script, added up in case it was entered on more than one page
+	// script_marks[part][marker][ExamNo] = 4
+	script_marks := make(map[string]map[string]map[string]float64)
+	for _, entry := range form_values {
+		partnum, moderation, ok := markField(entry.FieldName)
+		if !ok || moderation || !hasContent(entry.Value) {
+			continue
+		}
+		pname, known := part_name[partnum]
+		mark, err := parseMark(entry.Value)
+		if !known || err != nil {
+			continue
+		}
+		if script_marks[pname] == nil {
+			script_marks[pname] = make(map[string]map[string]float64)
+		}
+		if script_marks[pname][entry.Marker] == nil {
+			script_marks[pname][entry.Marker] = make(map[string]float64)
+		}
+		script_marks[pname][entry.Marker][entry.ExamNumber] = script_marks[pname][entry.Marker][entry.ExamNumber] + mark
+	}
+
+	comparisons := []MarkerComparison{}
+	for pnum := range parts {
+		pname, ok := part_name[pnum]
+		if !ok || len(script_marks[pname]) < 2 {
+			continue // nothing to compare with if only one marker marked this part
+		}
+
+		by_marker := make(map[string][]float64)
+		for marker, marks := range script_marks[pname] {
+			for _, mark := range marks {
+				by_marker[marker] = append(by_marker[marker], mark)
+			}
+		}
+
+		markers := make([]string, 0, len(by_marker))
+		for marker := range by_marker {
+			markers = append(markers, marker)
+		}
+		sort.Strings(markers)
+
+		for _, marker := range markers {
+			marks := by_marker[marker]
+			others := []float64{}
+			for _, other := range markers {
+				if other != marker {
+					others = append(others, by_marker[other]...)
+				}
+			}
+			comparison := MarkerComparison{
+				Part:        pname,
+				Marker:      marker,
+				Count:       len(marks),
+				Mean:        mean(marks),
+				Median:      median(marks),
+				SD:          standardDeviation(marks),
+				OthersCount: len(others),
+				OthersMean:  mean(others),
+				OthersSD:    standardDeviation(others),
+			}
+			difference := comparison.Mean - comparison.OthersMean
+			if comparison.OthersSD > 0 {
+				comparison.EffectSize = difference / comparison.OthersSD
+			}
+			standard_error := math.Sqrt(comparison.SD*comparison.SD/float64(comparison.Count) + comparison.OthersSD*comparison.OthersSD/float64(comparison.OthersCount))
+			if standard_error > 0 {
+				comparison.Z = difference / standard_error
+				switch {
+				case comparison.Z > z_threshold:
+					comparison.Flag = LeniencyLenient
+				case comparison.Z < -z_threshold:
+					comparison.Flag = LeniencySevere
+				}
+			}
+			comparisons = append(comparisons, comparison)
+		}
+	}
+	return comparisons
+}
+
+// WriteMarkerComparisonCSV saves the comparison of markers, with the statistics to 2 decimal places
+func WriteMarkerComparisonCSV(comparisons []MarkerComparison, outputCSV string) error {
+
+	type row struct {
+		Part        string `csv:"Part"`
+		Marker      string `csv:"Marker"`
+		Count       int    `csv:"Count"`
+		Mean        string `csv:"Mean"`
+		Median      string `csv:"Median"`
+		SD          string `csv:"SD"`
+		OthersCount int    `csv:"OthersCount"`
+		OthersMean  string `csv:"OthersMean"`
+		OthersSD    string `csv:"OthersSD"`
+		EffectSize  string `csv:"EffectSize"`
+		Z           string `csv:"Z"`
+		Flag        string `csv:"Flag"`
+	}
+	dp := func(x float64) string { return strconv.FormatFloat(x, 'f', 2, 64) }
+
+	rows := make([]row, 0, len(comparisons))
+	for _, c := range comparisons {
+		rows = append(rows, row{c.Part, c.Marker, c.Count, dp(c.Mean), dp(c.Median), dp(c.SD), c.OthersCount, dp(c.OthersMean), dp(c.OthersSD), dp(c.EffectSize), dp(c.Z), c.Flag})
+	}
+
+	file, err := os.OpenFile(outputCSV, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gocsv.MarshalFile(&rows, file)
+}
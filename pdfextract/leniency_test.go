@@ -0,0 +1,35 @@
+package pdfextract
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestCompareMarkers(t *testing.T) {
+
+	form_values := []FormValues{}
+	for i, mark := range []string{"6", "6", "5", "6"} {
+		form_values = append(form_values, markEntry(fmt.Sprintf("B00000%d", i), "GK", 1, 1, mark))
+	}
+	for i, mark := range []string{"1", "2", "1", "2"} {
+		form_values = append(form_values, markEntry(fmt.Sprintf("B00001%d", i), "JS", 1, 1, mark))
+	}
+	form_values = append(form_values, markEntry("B000000", "GK", 1, 0, "3")) // only GK marked 1a
+
+	comparisons := CompareMarkers(form_values, testParts, 1.5)
+
+	if len(comparisons) != 2 {
+		t.Fatalf("got %+v", comparisons)
+	}
+	gk, js := comparisons[0], comparisons[1]
+	if gk.Part != "1b" || gk.Marker != "GK" || gk.Count != 4 || gk.Mean != 5.75 || gk.Median != 6 || gk.Flag != LeniencyLenient {
+		t.Errorf("GK: %+v", gk)
+	}
+	if js.Marker != "JS" || js.Mean != 1.5 || js.OthersMean != 5.75 || js.OthersCount != 4 || js.Flag != LeniencySevere {
+		t.Errorf("JS: %+v", js)
+	}
+	if math.Abs(gk.OthersMean-1.5) > 1e-9 || math.Abs(gk.Z+js.Z) > 1e-9 {
+		t.Errorf("expected opposite z scores, got %v and %v", gk.Z, js.Z)
+	}
+}
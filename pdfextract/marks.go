@@ -0,0 +1,150 @@
+package pdfextract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// How the script totals are rounded once the (possibly fractional) part marks have been added up
+type Rounding string
+
+const (
+	RoundNone    Rounding = "none"    // keep the exact total, e.g. 37.5
+	RoundNearest Rounding = "nearest" // round half up to a whole mark, e.g. 37.5 -> 38
+	RoundUp      Rounding = "up"      // always round up to a whole mark
+	RoundDown    Rounding = "down"    // always round down to a whole mark
+)
+
+// Options that control how ValidateMarking treats the marks
+type ValidationOptions struct {
+	TotalRounding  Rounding
+	HistogramWidth float64         // width of each band of the histogram of totals, as a percentage of the marks available (default 10)
+	Grades         GradeBoundaries // if given, each script gets a percentage and a grade
+	Scaling        *Scaling        // if given, each script gets a scaled total (and the grade is worked out from it)
+}
+
+func ParseRounding(str string) (Rounding, error) {
+	switch r := Rounding(strings.ToLower(strings.TrimSpace(str))); r {
+	case "":
+		return RoundNone, nil
+	case RoundNone, RoundNearest, RoundUp, RoundDown:
+		return r, nil
+	}
+	return RoundNone, fmt.Errorf("unknown rounding %q (use none, nearest, up or down)", str)
+}
+
+// parseMark reads the value typed into a mark field, e.g. "4", "2.5" or "2,5"
+func parseMark(str string) (float64, error) {
+	str = string(bytes.Trim([]byte(str), "\xfe\xf0\x00")) // fix bug with Edge prepending values with þÿ
+	str = strings.TrimSpace(str)
+	str = strings.Replace(str, ",", ".", 1) // allow a decimal comma
+	if len(str) == 0 {
+		return 0, errors.New("empty mark")
+	}
+	mark, err := strconv.ParseFloat(str, 64)
+	if err != nil || math.IsNaN(mark) || math.IsInf(mark, 0) {
+		return 0, fmt.Errorf("non-numeric mark %q", str)
+	}
+	return mark, nil
+}
+
+// onGranularity checks that mark is a whole number of steps, e.g. a multiple of 0.5
+func onGranularity(mark float64, step float64) bool {
+	if step <= 0 {
+		return true
+	}
+	steps := mark / step
+	return math.Abs(steps-math.Round(steps)) < 1e-6
+}
+
+func roundTotal(total float64, rounding Rounding) float64 {
+	// tidy up any floating point noise first, so that 0.1+0.2 is treated as 0.3
+	total = math.Round(total*1e6) / 1e6
+	switch rounding {
+	case RoundNearest:
+		return math.Floor(total + 0.5)
+	case RoundUp:
+		return math.Ceil(total)
+	case RoundDown:
+		return math.Floor(total)
+	}
+	return total
+}
+
+// scriptTotal adds up a script's counted marks, with the best answers to each choice of optional questions
+func scriptTotal(counted map[string][]string, part_max map[string]float64, choices []ChoiceGroup, optional_part map[string]bool, rounding Rounding) float64 {
+	total := 0.0
+	for _, choice := range choices {
+		total = total + choice.score(counted).Total
+	}
+	for pname := range part_max {
+		if !optional_part[pname] {
+			total = total + sumOfMarks(counted[pname])
+		}
+	}
+	return roundTotal(total, rounding)
+}
+
+// formatMark writes a mark without trailing zeros, e.g. 4, 2.5 or 0.25
+func formatMark(mark float64) string {
+	return strconv.FormatFloat(math.Round(mark*100)/100, 'f', -1, 64)
+}
+
+// validateMark parses the mark awarded for a part, and describes the problem with it (if any)
+func validateMark(str string, part_max float64, step float64) (float64, string) {
+	mark, err := parseMark(str)
+	if err != nil {
+		return 0, "non-numeric mark"
+	}
+	if mark < 0 {
+		return mark, "negative mark"
+	}
+	if mark > part_max {
+		return mark, "max mark is " + formatMark(part_max)
+	}
+	if !onGranularity(mark, step) {
+		return mark, "marks must be in steps of " + formatMark(step)
+	}
+	return mark, ""
+}
+
+// countedMarks replaces any mark that is negative or over the marks available for its part with 0, so that it is
+// flagged by the validation but counts towards the totals and statistics like a part that has not been marked.
+// The part still counts as attempted when working out the choice questions.
+func countedMarks(marks_by_part map[string][]string, part_max map[string]float64) map[string][]string {
+	counted := make(map[string][]string)
+	for pname, values := range marks_by_part {
+		counted[pname] = []string{}
+		for _, value := range values {
+			if mark, err := parseMark(value); err == nil && (mark < 0 || mark > part_max[pname]) {
+				value = "0"
+			}
+			counted[pname] = append(counted[pname], value)
+		}
+	}
+	return counted
+}
+
+// markField picks out the part number from the name of a mark field, e.g. "qn-part-mark-3" is part 3,
+// and "qn-part-moderate-3" is the moderated mark for part 3
+func markField(field_name string) (partnum int, moderation bool, ok bool) {
+	for prefix, is_moderation := range map[string]bool{"qn-part-mark-": false, "qn-part-moderate-": true} {
+		if strings.HasPrefix(field_name, prefix) {
+			partnum, err := strconv.Atoi(strings.TrimPrefix(field_name, prefix))
+			return partnum, is_moderation, err == nil
+		}
+	}
+	return 0, false, false
+}
+
+// roundToStep rounds a mark (half up) to the nearest step that the part allows, e.g. 3.25 -> 3.5 in half marks
+func roundToStep(mark float64, step float64) float64 {
+	if step <= 0 {
+		return mark
+	}
+	return math.Floor(mark/step+0.5+1e-9) * step
+}
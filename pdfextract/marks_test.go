@@ -0,0 +1,72 @@
+package pdfextract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateMark(t *testing.T) {
+
+	tests := []struct {
+		value   string
+		max     float64
+		step    float64
+		mark    float64
+		problem string
+	}{
+		{"4", 5, 1, 4, ""},
+		{"2.5", 5, 0.5, 2.5, ""},
+		{"2,5", 5, 0.5, 2.5, ""},
+		{"2.5", 5, 1, 2.5, "marks must be in steps of 1"},
+		{"2.25", 5, 0.5, 2.25, "marks must be in steps of 0.5"},
+		{"0.75", 1, 0.25, 0.75, ""},
+		{"5.5", 5, 0.5, 5.5, "max mark is 5"},
+		{"-1", 5, 1, -1, "negative mark"},
+		{"four", 5, 1, 0, "non-numeric mark"},
+		{"\xfe\xf03", 5, 1, 3, ""},
+	}
+
+	for _, test := range tests {
+		mark, problem := validateMark(test.value, test.max, test.step)
+		if mark != test.mark || problem != test.problem {
+			t.Errorf("validateMark(%q, %v, %v) = %v, %q; want %v, %q", test.value, test.max, test.step, mark, problem, test.mark, test.problem)
+		}
+	}
+}
+
+func TestRoundTotal(t *testing.T) {
+
+	tests := []struct {
+		total    float64
+		rounding Rounding
+		want     float64
+	}{
+		{37.5, RoundNone, 37.5},
+		{37.5, RoundNearest, 38},
+		{37.25, RoundNearest, 37},
+		{37.5, RoundUp, 38},
+		{37.5, RoundDown, 37},
+		{0.1 + 0.2, RoundNone, 0.3},
+		{3, RoundUp, 3},
+	}
+
+	for _, test := range tests {
+		if got := roundTotal(test.total, test.rounding); got != test.want {
+			t.Errorf("roundTotal(%v, %s) = %v; want %v", test.total, test.rounding, got, test.want)
+		}
+	}
+
+	if got := formatMark(2.50); got != "2.5" {
+		t.Errorf("formatMark(2.5) = %s", got)
+	}
+}
+
+func TestCountedMarks(t *testing.T) {
+
+	marks_by_part := map[string][]string{"1a": {"4"}, "1b": {"60"}, "2": {"-2", "3"}, "3": {"four"}, "4": {}}
+	part_max := map[string]float64{"1a": 4, "1b": 6, "2": 10, "3": 5, "4": 5}
+	want := map[string][]string{"1a": {"4"}, "1b": {"0"}, "2": {"0", "3"}, "3": {"four"}, "4": {}}
+	if got := countedMarks(marks_by_part, part_max); !reflect.DeepEqual(got, want) {
+		t.Errorf("countedMarks = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,249 @@
+package pdfextract
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ModerationAudit records what moderation changed: each moderated part of each script, and the overall shift in
+// each marker's marks
+type ModerationAudit struct {
+	Parts   []ModeratedPart `json:"parts"`   // sorted by exam number, then in the order of the parts csv
+	Markers []MarkerShift   `json:"markers"` // sorted by marker
+}
+
+// A part of a script where a moderated mark has been entered
+type ModeratedPart struct {
+	ExamNumber string   `json:"exam_number"`
+	Part       string   `json:"part"`
+	Markers    []string `json:"markers"`   // who entered the original mark
+	Original   []string `json:"original"`  // the marks entered by the marker, e.g. ["4"]
+	Moderated  []string `json:"moderated"` // the marks entered by the moderator
+	Delta      *float64 `json:"delta"`     // moderated - original, or nil if either is not a number
+	Moderators []string `json:"moderators"`
+}
+
+// The effect of moderation on the marks given by one marker
+type MarkerShift struct {
+	Marker     string  `json:"marker"`
+	Moderated  int     `json:"moderated"` // number of parts moderated
+	Raised     int     `json:"raised"`
+	Lowered    int     `json:"lowered"`
+	Unchanged  int     `json:"unchanged"`
+	TotalShift float64 `json:"total_shift"` // sum of the deltas
+	MeanShift  float64 `json:"mean_shift"`  // mean delta over the moderated parts
+}
+
+// AuditModeration compares the original marks with the moderated marks (from the qn-part-moderate-N fields)
+func AuditModeration(form_values []FormValues, parts []*PaperStructure) *ModerationAudit {
+
+	part_name := make(map[int]string)
+	for pnum, part := range parts {
+		if part.Part != "" {
+			part_name[pnum] = part.Label()
+		}
+	}
+
+	type partEntries struct {
+		markers, moderators           map[string]bool
+		original_list, moderated_list []string
+	}
+	newPartEntries := func() *partEntries {
+		return &partEntries{markers: map[string]bool{}, moderators: map[string]bool{}}
+	}
+
+	// entries[ExamNo][pnum]
+	entries := make(map[string]map[int]*partEntries)
+	for _, entry := range form_values {
+		partnum, moderation, ok := markField(entry.FieldName)
+		if !ok || !hasContent(entry.Value) {
+			continue
+		}
+		if _, known := part_name[partnum]; !known {
+			continue
+		}
+		if entries[entry.ExamNumber] == nil {
+			entries[entry.ExamNumber] = make(map[int]*partEntries)
+		}
+		if entries[entry.ExamNumber][partnum] == nil {
+			entries[entry.ExamNumber][partnum] = newPartEntries()
+		}
+		pe := entries[entry.ExamNumber][partnum]
+		value := strings.TrimSpace(entry.Value)
+		if moderation {
+			pe.moderated_list = append(pe.moderated_list, value)
+			pe.moderators[entry.Marker] = true
+		} else {
+			pe.original_list = append(pe.original_list, value)
+			pe.markers[entry.Marker] = true
+		}
+	}
+
+	examnos := make([]string, 0, len(entries))
+	for ExamNo := range entries {
+		examnos = append(examnos, ExamNo)
+	}
+	sort.Strings(examnos)
+
+	audit := &ModerationAudit{Parts: []ModeratedPart{}, Markers: []MarkerShift{}}
+	shifts := make(map[string]*MarkerShift)
+	for _, ExamNo := range examnos {
+		for pnum := range parts {
+			pe, ok := entries[ExamNo][pnum]
+			if !ok || len(pe.moderated_list) == 0 {
+				continue
+			}
+			moderated_part := ModeratedPart{
+				ExamNumber: ExamNo,
+				Part:       part_name[pnum],
+				Markers:    sortedKeys(pe.markers),
+				Original:   append([]string{}, pe.original_list...),
+				Moderated:  pe.moderated_list,
+				Moderators: sortedKeys(pe.moderators),
+			}
+			original, err_original := sumOfValues(pe.original_list)
+			moderated, err_moderated := sumOfValues(pe.moderated_list)
+			if err_original == nil && err_moderated == nil {
+				delta := moderated - original
+				moderated_part.Delta = &delta
+			}
+			audit.Parts = append(audit.Parts, moderated_part)
+
+			for _, marker := range moderated_part.Markers {
+				if shifts[marker] == nil {
+					shifts[marker] = &MarkerShift{Marker: marker}
+				}
+				shift := shifts[marker]
+				shift.Moderated++
+				if moderated_part.Delta == nil {
+					continue
+				}
+				switch delta := *moderated_part.Delta; {
+				case delta > 0:
+					shift.Raised++
+				case delta < 0:
+					shift.Lowered++
+				default:
+					shift.Unchanged++
+				}
+				shift.TotalShift = shift.TotalShift + *moderated_part.Delta
+			}
+		}
+	}
+
+	for _, shift := range shifts {
+		if counted := shift.Raised + shift.Lowered + shift.Unchanged; counted > 0 {
+			shift.MeanShift = shift.TotalShift / float64(counted)
+		}
+		audit.Markers = append(audit.Markers, *shift)
+	}
+	sort.Slice(audit.Markers, func(i, j int) bool { return audit.Markers[i].Marker < audit.Markers[j].Marker })
+	return audit
+}
+
+// applyModeration lays one script's moderated marks over the marks entered by the marker. A part that has been
+// moderated takes the moderated marks in place of the originals, and the validation problems with the original marks
+// are swapped for any problems with the moderated ones. Other scripts are not affected.
+func applyModeration(marked map[string][]string, moderated map[string][]string, validation map[string]string, moderation_validation map[string]string) map[string][]string {
+	marks := make(map[string][]string, len(marked))
+	for pname, values := range marked {
+		marks[pname] = values
+	}
+	for pname, values := range moderated {
+		if len(values) == 0 {
+			continue
+		}
+		marks[pname] = values
+		if validation == nil {
+			continue
+		}
+		delete(validation, pname)
+		if problem, ok := moderation_validation[pname]; ok {
+			validation[pname] = problem
+		}
+	}
+	return marks
+}
+
+// sumOfValues adds up the marks entered in a part, or gives an error if one of them is not a number
+func sumOfValues(values []string) (float64, error) {
+	sum := 0.0
+	for _, value := range values {
+		mark, err := parseMark(value)
+		if err != nil {
+			return 0, err
+		}
+		sum = sum + mark
+	}
+	return sum, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteModerationCSV writes the moderated parts, followed by the shift in each marker's marks
+func WriteModerationCSV(audit *ModerationAudit, outputCSV string) error {
+
+	file, err := os.OpenFile(outputCSV, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+
+	w.Write([]string{"Moderated parts (" + strconv.Itoa(len(audit.Parts)) + "):"})
+	w.Write([]string{"Exam Number", "Part", "Marker", "Original", "Moderated", "Delta", "Moderator"})
+	for _, part := range audit.Parts {
+		delta := ""
+		if part.Delta != nil {
+			delta = formatMark(*part.Delta)
+		}
+		w.Write([]string{
+			part.ExamNumber,
+			part.Part,
+			sliceToCommaString(part.Markers),
+			strings.Join(part.Original, " + "),
+			strings.Join(part.Moderated, " + "),
+			delta,
+			sliceToCommaString(part.Moderators),
+		})
+	}
+
+	w.Write([]string{""})
+	w.Write([]string{"Shift per marker:"})
+	w.Write([]string{"Marker", "Parts Moderated", "Raised", "Lowered", "Unchanged", "Total Shift", "Mean Shift"})
+	for _, shift := range audit.Markers {
+		w.Write([]string{
+			shift.Marker,
+			strconv.Itoa(shift.Moderated),
+			strconv.Itoa(shift.Raised),
+			strconv.Itoa(shift.Lowered),
+			strconv.Itoa(shift.Unchanged),
+			formatMark(shift.TotalShift),
+			strconv.FormatFloat(shift.MeanShift, 'f', 2, 64),
+		})
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// WriteModerationJSON writes the moderation audit for other programs to read
+func WriteModerationJSON(audit *ModerationAudit, outputJSON string) error {
+	json_data, err := json.MarshalIndent(audit, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(outputJSON, json_data, os.ModePerm)
+}
@@ -0,0 +1,106 @@
+package pdfextract
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// moderationEntry is the form value for a moderated mark for part pnum on page (counting from 1)
+func moderationEntry(examno string, moderator string, page int, pnum int, value string) FormValues {
+	entry := markEntry(examno, moderator, page, pnum, value)
+	entry.Field = strings.Replace(entry.Field, "qn-part-mark-", "qn-part-moderate-", 1)
+	entry.FieldName = strings.Replace(entry.FieldName, "qn-part-mark-", "qn-part-moderate-", 1)
+	return entry
+}
+
+func TestAuditModeration(t *testing.T) {
+
+	form_values := []FormValues{
+		markEntry("B000001", "GK", 1, 0, "3"),
+		markEntry("B000001", "GK", 1, 1, "6"),
+		moderationEntry("B000001", "JS", 1, 1, "4"),
+		markEntry("B000002", "GK", 1, 0, "2"),
+		moderationEntry("B000002", "JS", 1, 0, "3.5"),
+		markEntry("B000003", "AB", 2, 2, "7"),
+		moderationEntry("B000003", "JS", 2, 2, "7"),
+		markEntry("B000004", "AB", 2, 2, "8"),
+	}
+
+	audit := AuditModeration(form_values, testParts)
+
+	if len(audit.Parts) != 3 {
+		t.Fatalf("got %d moderated parts: %+v", len(audit.Parts), audit.Parts)
+	}
+	first := audit.Parts[0]
+	if first.ExamNumber != "B000001" || first.Part != "1b" || *first.Delta != -2 ||
+		!reflect.DeepEqual(first.Markers, []string{"GK"}) || !reflect.DeepEqual(first.Moderators, []string{"JS"}) {
+		t.Errorf("got %+v", first)
+	}
+
+	want := []MarkerShift{
+		{Marker: "AB", Moderated: 1, Unchanged: 1},
+		{Marker: "GK", Moderated: 2, Raised: 1, Lowered: 1, TotalShift: -0.5, MeanShift: -0.25},
+	}
+	if !reflect.DeepEqual(audit.Markers, want) {
+		t.Errorf("got shifts %+v", audit.Markers)
+	}
+}
+
+func TestApplyModeration(t *testing.T) {
+
+	marked := map[string][]string{"1a": {"3"}, "1b": {"9"}, "2": {"4"}}
+	moderated := map[string][]string{"1b": {"5"}, "2": {"x"}}
+	validation := map[string]string{"1b": "max mark is 6"}
+
+	marks := applyModeration(marked, moderated, validation, map[string]string{"2": "non-numeric mark"})
+
+	want := map[string][]string{"1a": {"3"}, "1b": {"5"}, "2": {"x"}}
+	if !reflect.DeepEqual(marks, want) {
+		t.Errorf("got marks %v", marks)
+	}
+	if !reflect.DeepEqual(validation, map[string]string{"2": "non-numeric mark"}) {
+		t.Errorf("got validation %v", validation)
+	}
+	if marked["1b"][0] != "9" {
+		t.Errorf("the original marks were changed: %v", marked)
+	}
+}
+
+// Moderating one script should not change the marks or statistics of any other script
+func TestSummariseMarkingWithModeration(t *testing.T) {
+
+	form_values := []FormValues{
+		markEntry("B000001", "GK", 1, 0, "3"),
+		moderationEntry("B000001", "JS", 1, 0, "2"),
+		moderationEntry("B000001", "JS", 1, 2, "9"), // moderated on an earlier page than the original mark
+		markEntry("B000001", "GK", 1, 1, "6"),
+		markEntry("B000001", "GK", 2, 2, "12"),
+		markEntry("B000002", "GK", 1, 0, "4"),
+		markEntry("B000002", "GK", 1, 1, "5"),
+		markEntry("B000002", "GK", 2, 2, "8"),
+		markEntry("B000003", "GK", 1, 1, "3"),
+		seenEntry("B000003", "GK", 2),
+	}
+
+	summary := SummariseMarking(form_values, testParts, ValidationOptions{})
+
+	moderated, other, missing := summary.Scripts[0], summary.Scripts[1], summary.Scripts[2]
+	if moderated.Status != StatusComplete || moderated.Total != 17 || moderated.Marks["1a"] != 2 {
+		t.Errorf("B000001: %+v", moderated)
+	}
+	if other.Status != StatusComplete || other.Total != 17 || other.Marks["1a"] != 4 {
+		t.Errorf("B000002: %+v", other)
+	}
+	want_validation := map[string]string{"1a": "not marked", "2": "not marked"}
+	if missing.Status != StatusInvalid || !reflect.DeepEqual(missing.Validation, want_validation) {
+		t.Errorf("B000003: %+v", missing)
+	}
+
+	if mean := summary.Parts[0].Mean; summary.Parts[0].Name != "1a" || mean == nil || *mean != 2 {
+		t.Errorf("got 1a column %+v", summary.Parts[0])
+	}
+	if mean := summary.Statistics.Mean; mean == nil || *mean != 37.0/3 {
+		t.Errorf("got mean total %v", mean)
+	}
+}
@@ -9,7 +9,6 @@ package pdfextract
 
 import (
 	"encoding/json"
-	"encoding/csv"
 	"errors"
 	"fmt"
 	"os"
@@ -18,12 +17,11 @@ import (
 	"strings"
 	"regexp"
 	"sort"
+	"sync"
 	"bytes"
 
 	"github.com/gocarina/gocsv"
 	"github.com/timdrysdale/parselearn"
-	extractor "github.com/timdrysdale/unipdf/v3/extractor"
-	pdf "github.com/timdrysdale/unipdf/v3/model"
 )
 
 type FormValues struct {
@@ -37,9 +35,18 @@ type FormValues struct {
 }
 
 // Structure for the optional reading a csv of parts and marks
+// granularity is the smallest step a mark can be given in (e.g. 0.5 for half marks) - if blank, only whole marks are allowed
+// section, question and subpart are optional, and are used to add subtotals to the summary (see structure.go)
+// choice and choose mark optional questions, e.g. choice "B" with choose 3 to answer 3 questions from B (see choice.go)
 type PaperStructure struct {
-	Part       string  `csv:"part"`
-	Marks      int     `csv:"marks"`
+	Section     string  `csv:"section"`
+	Question    string  `csv:"question"`
+	Part        string  `csv:"part"`
+	Subpart     string  `csv:"subpart"`
+	Marks       float64 `csv:"marks"`
+	Granularity float64 `csv:"granularity"`
+	Choice      string  `csv:"choice"`
+	Choose      int     `csv:"choose"`
 }
 
 type cmdOptions struct {
@@ -92,17 +99,45 @@ func GetPartsAndMarks(csv_path string) []*PaperStructure {
 	if err := gocsv.UnmarshalFile(marksFile, &parts); err != nil {
 		panic(err)
 	}
+	for _, part := range parts {
+		if part.Granularity == 0 {
+			part.Granularity = 1
+		}
+	}
 	return parts
 }
 
-func ReadFormsInDirectory(formsPath string, outputCSV string) []FormValues {
+// Options that control how ReadFormsInDirectory finds and reads the scripts
+type ExtractOptions struct {
+	Workers     int          // number of scripts to read at once
+	Conventions *Conventions // filename and header patterns (default: DefaultConventions)
+	CachePath   string       // where to keep the extracted values between runs, so that unchanged scripts are not read again (no cache if empty)
+}
 
-	form_vals := []FormValues{}
-	
-	filename_examno, err := regexp.Compile("(B[0-9]{6})-.*.pdf")
+// ReadFormsInDirectory extracts the form values from every script in formsPath, using up to opts.Workers
+// scripts at once. The values are returned (and saved to outputCSV, unless it is empty) in the order of the script
+// filenames, so the output is the same however many workers are used.
+// Scripts that cannot be read are left out, and the problems with them are returned instead.
+// With a cache, only new or changed scripts are read, and the changes since the last run are returned.
+func ReadFormsInDirectory(formsPath string, outputCSV string, opts ExtractOptions) ([]FormValues, []ScriptError, ScriptChanges) {
+
+	conv := opts.Conventions
+	if conv == nil {
+		conv = DefaultConventions()
+	}
 	
-	var num_scripts int
-	filepath.Walk(formsPath, func(path string, f os.FileInfo, _ error) error {
+	// First find the scripts to process
+	type script struct {
+		path   string
+		examno string
+	}
+	scripts := []script{}
+	walk_errors := []ScriptError{}
+	filepath.Walk(formsPath, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			walk_errors = append(walk_errors, ScriptError{path, StageWalk, err.Error()})
+			return nil
+		}
 		//if f.IsDir() && strings.Contains(f.Name(), "Moderation") { // TODO - check that this does not prevent us checking moderated marks!
 		//	return filepath.SkipDir
 		//}
@@ -111,54 +146,193 @@ func ReadFormsInDirectory(formsPath string, outputCSV string) []FormValues {
 			if filepath.Ext(f.Name()) != ".pdf" {
 				return nil
 			}
-			proper_filename := filename_examno.MatchString(f.Name())
+			extracted_examno, proper_filename := conv.ExamNumberFromFilename(f.Name())
 			if proper_filename {
-				extracted_examno := filename_examno.FindStringSubmatch(f.Name())[1]
-				vals_on_this_form := ReadFormFromPDF(path, true)
-				// check that extracted_examno matches the one on the script!
-				if vals_on_this_form[0].ExamNumber != extracted_examno {
-					fmt.Println(" - Exam number mismatch: file",path,"has value",vals_on_this_form[0].ExamNumber)
-				}
-				
-				form_vals = append(form_vals, vals_on_this_form...)
-				num_scripts++
+				scripts = append(scripts, script{path, extracted_examno})
 			} else {
 				fmt.Println(" - Malformed filename: ", f.Name())
+				walk_errors = append(walk_errors, ScriptError{path, StageFilename, "malformed filename"})
 			}
 		}
 		return nil
 	})
 	
+	var cache *extractionCache
+	if opts.CachePath != "" {
+		cache = loadExtractionCache(opts.CachePath, conv.fingerprint())
+	}
+	
+	// Then extract the values from each script - each worker saves its results in the slot for that script
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	vals_by_script := make([][]FormValues, len(scripts))
+	errors_by_script := make([][]ScriptError, len(scripts))
+	hash_by_script := make([]string, len(scripts))
+	from_cache := make([]bool, len(scripts))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var vals_on_this_form []FormValues
+				if cache != nil {
+					hash, err := hashFile(scripts[i].path)
+					if err != nil {
+						fmt.Println(" - ", err)
+						errors_by_script[i] = append(errors_by_script[i], ScriptError{scripts[i].path, StageOpen, err.Error()})
+						continue
+					}
+					hash_by_script[i] = hash
+					vals_on_this_form, from_cache[i] = cache.Scripts[hash]
+				}
+				if !from_cache[i] {
+					var err error
+					vals_on_this_form, err = ReadFormFromPDF(scripts[i].path, true, conv)
+					if err != nil {
+						fmt.Println(" - ", err)
+						errors_by_script[i] = append(errors_by_script[i], asScriptError(scripts[i].path, err))
+						continue
+					}
+				}
+				// check that extracted_examno matches the one on the script!
+				if vals_on_this_form[0].ExamNumber != scripts[i].examno {
+					fmt.Println(" - Exam number mismatch: file",scripts[i].path,"has value",vals_on_this_form[0].ExamNumber)
+					errors_by_script[i] = append(errors_by_script[i], ScriptError{scripts[i].path, StageExamNumber,
+						fmt.Sprintf("filename has %s but the header has %s", scripts[i].examno, vals_on_this_form[0].ExamNumber)})
+				}
+				vals_by_script[i] = vals_on_this_form
+			}
+		}()
+	}
+	for i := range scripts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	
+	form_vals := []FormValues{}
+	script_errors := walk_errors
+	for i, vals_on_this_form := range vals_by_script {
+		form_vals = append(form_vals, vals_on_this_form...)
+		script_errors = append(script_errors, errors_by_script[i]...)
+	}
+	fmt.Printf("Extracted form values from %d scripts (%d problems)\n", len(scripts), len(script_errors))
+	
+	// Update the cache with the scripts as they are now, and report what has changed
+	changes := ScriptChanges{}
+	if cache != nil {
+		hashes := make(map[string]string)
+		scripts_now := make(map[string][]FormValues)
+		for i := range scripts {
+			if hash_by_script[i] == "" {
+				continue
+			}
+			hashes[scripts[i].path] = hash_by_script[i]
+			if vals_by_script[i] != nil {
+				scripts_now[hash_by_script[i]] = vals_by_script[i]
+			}
+		}
+		changes = cache.compare(hashes)
+		for i := range scripts {
+			if from_cache[i] {
+				changes.Unchanged++
+			}
+		}
+		fmt.Printf("%d scripts unchanged since the last run, %d new, %d changed, %d removed\n",
+			changes.Unchanged, len(changes.New), len(changes.Changed), len(changes.Removed))
+		cache.Files = hashes
+		cache.Scripts = scripts_now
+		if err := cache.save(opts.CachePath); err != nil {
+			fmt.Println(" - Could not save the cache:", err)
+		}
+	}
 	
-	file, err := os.OpenFile(outputCSV, os.O_RDWR|os.O_CREATE, os.ModePerm)
+	if outputCSV == "" {
+		return form_vals, script_errors, changes
+	}
+	file, err := os.OpenFile(outputCSV, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
 	if err != nil {
 		fmt.Println(err)
-		return nil
+		return form_vals, script_errors, changes
 	}
 	defer file.Close()
 	gocsv.MarshalFile(form_vals, file)
 	
-	return form_vals
+	return form_vals, script_errors, changes
 }
 
-func ReadFormFromPDF(path string, include_nonempty_values bool) []FormValues {
+// asScriptError makes sure that any error from reading a script is recorded as a ScriptError
+func asScriptError(path string, err error) ScriptError {
+	if script_err, ok := err.(ScriptError); ok {
+		return script_err
+	}
+	return ScriptError{path, StageOpen, err.Error()}
+}
 
+// ReadFormValuesCSV reads back a raw form values csv written by ReadFormsInDirectory
+func ReadFormValuesCSV(csv_path string) ([]FormValues, error) {
+
+	file, err := os.Open(csv_path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	form_vals := []FormValues{}
+	if err := gocsv.UnmarshalFile(file, &form_vals); err != nil {
+		return nil, err
+	}
+	return form_vals, nil
+}
+
+// ReadFormFromPDF reads the header details and form values from the script at path
+// The first entry has the header details only, and the rest are the form fields
+func ReadFormFromPDF(path string, include_nonempty_values bool, conv *Conventions) ([]FormValues, error) {
+
+	script, err := OpenScript(path)
+	if err != nil {
+		return nil, ScriptError{path, StageOpen, err.Error()}
+	}
+	defer script.Close()
+
+	return ReadFormFromScript(script, include_nonempty_values, conv)
+}
+
+// ReadFormFromScript reads the header details and form values from a script that has already been opened
+// The header is read using conv (or the default conventions if conv is nil)
+func ReadFormFromScript(script *Script, include_nonempty_values bool, conv *Conventions) ([]FormValues, error) {
+
+	if conv == nil {
+		conv = DefaultConventions()
+	}
+
+	path := script.Path
 	form_vals := FormValues{}
 	
 	// Read the text values from the PDF
-	var opt cmdOptions
-	text_data, _ := getText(path, opt)
+	text_data, err := script.HeaderText()
+	if err != nil {
+		return nil, ScriptError{path, StageHeader, err.Error()}
+	}
 	
-	form_vals.Marker = extractMarkerInitials(text_data)
-	form_vals.CourseCode = extractCourseCode(text_data)
-	form_vals.ExamNumber = extractExamNumber(text_data)
+	form_vals.Marker = conv.extractMarkerInitials(text_data)
+	if form_vals.CourseCode, err = conv.extractCourseCode(text_data); err != nil {
+		return nil, ScriptError{path, StageHeader, err.Error()}
+	}
+	if form_vals.ExamNumber, err = conv.extractExamNumber(text_data); err != nil {
+		return nil, ScriptError{path, StageHeader, err.Error()}
+	}
 	
 	//fmt.Println("Course code: ",form_vals.CourseCode)
 	//fmt.Println("Marker initials: ",form_vals.Marker)
 	//fmt.Println("Exam number: ",form_vals.ExamNumber)
 	
 	// Read the form values from the PDF
-	field_data, _ := mapPdfFieldData(path)
+	field_data := script.Fields()
 	//PrettyPrintStruct(field_data)
 	
 	all_form_vals := []FormValues{form_vals}
@@ -180,6 +354,9 @@ func ReadFormFromPDF(path string, include_nonempty_values bool) []FormValues {
 		//page, fieldname := whatPageIsThisFrom(key)
 		//fmt.Println(key, page, fieldname)
 		this_form_entry.Page, this_form_entry.FieldName = whatPageIsThisFrom(key)
+		if this_form_entry.Page < 0 {
+			continue // nor fields that mention a page without a page number
+		}
 		page_marker := whoMarkedThisPage(key)
 		if page_marker != "" {
 			this_form_entry.Marker = page_marker
@@ -187,57 +364,92 @@ func ReadFormFromPDF(path string, include_nonempty_values bool) []FormValues {
 		all_form_vals = append(all_form_vals, this_form_entry)
 	}
 	
+	// the fields come out of a map, so sort them to keep the output in a consistent order
+	fields := all_form_vals[1:]
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	
 	fmt.Printf(" - Extracted %d entries for %s (%s)\n", form_values, form_vals.ExamNumber, path)
 	//PrettyPrintStruct(all_form_vals)
 	
-	return all_form_vals
+	return all_form_vals, nil
 }
 
-func ValidateMarking(form_values []FormValues, parts []*PaperStructure, outputCSV string) (error) {
+// ValidateMarking summarises the marks and writes the summary to outputCSV
+func ValidateMarking(form_values []FormValues, parts []*PaperStructure, outputCSV string, opts ValidationOptions) (error) {
+	return WriteSummaryCSV(SummariseMarking(form_values, parts, opts), outputCSV)
+}
+
+// SummariseMarking adds up the marks for each script and checks them against the parts of the paper
+func SummariseMarking(form_values []FormValues, parts []*PaperStructure, opts ValidationOptions) *MarksSummary {
 	
 	// understand the parts structure
-	marks_available := make(map[int]int)
+	marks_available := make(map[int]float64)
+	marks_granularity := make(map[int]float64)
 	part_name := make(map[int]string)
-	part_to_marks := make(map[string]int)
+	part_to_marks := make(map[string]float64)
 	for pnum, part := range parts {
 		if part.Part != "" {
 			marks_available[pnum] = part.Marks
-			part_name[pnum] = part.Part
-			part_to_marks[part.Part] = part.Marks
+			marks_granularity[pnum] = part.Granularity
+			part_name[pnum] = part.Label()
+			part_to_marks[part.Label()] = part.Marks
 		}
 	}
 	fmt.Println("Parts:",part_name,"\nMarks available:",marks_available)
 	
-	// Prepare a sorted list of the part names
-	partnames := make([]string, 0, len(part_name))
-	for  _, value := range part_name {
-	   partnames = append(partnames, value)
+	// Question/section subtotals are shown after the parts
+	subtotals := SubtotalGroups(parts)
+	column_outof := make(map[string]float64)
+	for pname, outof := range part_to_marks {
+		column_outof[pname] = outof
+	}
+	for _, group := range subtotals {
+		column_outof[group.Name] = group.Marks
+	}
+	
+	// Optional questions only count towards the total if they are among the best answers from their choice group
+	choices := ChoiceGroups(parts)
+	optional_part := make(map[string]bool)
+	for _, choice := range choices {
+		for _, qn := range choice.Questions {
+			for _, pname := range qn.Parts {
+				optional_part[pname] = true
+			}
+		}
 	}
-	sort.Strings(partnames)
 	
 	coursecode := ""
 	markers := make(map[string]bool)
+	script_markers := make(map[string]map[string]bool) // script_markers[ExamNo]["GK"] = true
 	
 	// Set up maps to store data
 	mark_details := make(map[string]map[string][]string) // mark_details[ExamNo][part] = [4,5,6]
 	moderation_details := make(map[string]map[string][]string) // moderation_details[ExamNo][part] = [4,5,6]
-	script_total := make(map[string]int) // script_total[ExamNo] = 7
-	//validation := make(map[string][]string) // validation[ExamNo] = ["1a has no mark", "1b noninteger mark"]
-	validation := make(map[string]map[string]string) // validation[ExamNo]["1a"] = "noninteger mark"
+	moderation_validation := make(map[string]map[string]string) // moderation_validation[ExamNo]["1a"] = "negative mark"
+	//validation := make(map[string][]string) // validation[ExamNo] = ["1a has no mark", "1b non-numeric mark"]
+	validation := make(map[string]map[string]string) // validation[ExamNo]["1a"] = "non-numeric mark"
 	marks_on_page := make(map[string]map[int]int) // marks_on_page[ExamNo][1] = 0
-	marks_awarded := make(map[string]int) // marks_awarded[part] = 50 - sum of all student marks on this question
-	marks_awarded_count := make(map[string]int) // marks_awarded[part] = 5 - number of students awarded marks
+	marks_awarded_count := make(map[string]int) // marks_awarded_count[part] = 5 - number of numeric marks on this question, after moderation
 	bad_pages := make(map[string][]int) // bad_pages[ExamNo] = [1,4,5]
 	
 	for _, entry := range form_values {
 		ExamNo := entry.ExamNumber
 		coursecode = entry.CourseCode
 		markers[entry.Marker] = true
+		if entry.Marker != "" {
+			if script_markers[ExamNo] == nil {
+				script_markers[ExamNo] = make(map[string]bool)
+			}
+			script_markers[ExamNo][entry.Marker] = true
+		}
 		
 		if !strings.Contains(entry.Field, "page") {
 			continue // quietly skip fields that don't have a page
 		}
 		page, field_name := whatPageIsThisFrom(entry.Field)
+		if page < 0 {
+			continue
+		}
 		
 		// Prepare nested maps to receive values
 		if _, ok := marks_on_page[ExamNo][page]; !ok {
@@ -278,24 +490,14 @@ func ValidateMarking(form_values []FormValues, parts []*PaperStructure, outputCS
 				mark_details[ExamNo][partname] = []string{}
 			}
 			
-			// Get the integer value
-			var mark_awarded int
+			// Get the numerical value
 			entry.Value = string(bytes.Trim([]byte(entry.Value), "\xfe\xf0\x00")) // fix bug with Edge prepending values with þÿ
 			entry.Value = strings.TrimSpace(entry.Value)
 			if len(entry.Value) == 0 { continue }
 			
-			if intval, err := strconv.Atoi(entry.Value); err == nil {
-				mark_awarded = intval
-				marks_awarded[partname] = marks_awarded[partname] + intval
-				marks_awarded_count[partname]++
-				script_total[ExamNo] = script_total[ExamNo] + intval
-			} else {
-				validation[ExamNo][partname] = "noninteger mark"
-			}
-			
-			// Validation of the value
-			if mark_awarded > part_max {
-				validation[ExamNo][partname] = "max mark is "+strconv.Itoa(part_max)
+			_, problem := validateMark(entry.Value, part_max, marks_granularity[partnum])
+			if problem != "" {
+				validation[ExamNo][partname] = problem
 			}
 			
 			mark_details[ExamNo][partname] = append(mark_details[ExamNo][partname], entry.Value)
@@ -303,37 +505,17 @@ func ValidateMarking(form_values []FormValues, parts []*PaperStructure, outputCS
 			
 		}	
 		
-		// Moderation fields have been used
+		// Moderation fields have been used - these are kept apart from the original marks, and laid over them below
 		if strings.HasPrefix(field_name, "qn-part-moderate-") && hasContent(entry.Value) {
 			partnum, _ := strconv.Atoi(strings.TrimPrefix(field_name, "qn-part-moderate-"))
 			partname := part_name[partnum]
 			part_max := marks_available[partnum]
-	
-			// If this is the first time a moderation value has been encountered for this part:
-			// - prepare structure to receive the marks
-			// - clear out existing values from the original marker
-			if moderation_details[ExamNo][partname] == nil {
-				moderation_details[ExamNo][partname] = []string{}
-				script_total[ExamNo] = script_total[ExamNo] - marks_awarded[partname]
-				marks_awarded[partname] = 0
-				marks_awarded_count[partname] = 0
-				delete(validation[ExamNo], partname)
-			}
 			
-			// Get the integer value
-			var mark_awarded int
-			if intval, err := strconv.Atoi(entry.Value); err == nil {
-				mark_awarded = intval
-				marks_awarded[partname] = marks_awarded[partname] + intval
-				marks_awarded_count[partname]++
-				script_total[ExamNo] = script_total[ExamNo] + intval
-			} else {
-				validation[ExamNo][partname] = "noninteger mark"
+			if moderation_validation[ExamNo] == nil {
+				moderation_validation[ExamNo] = make(map[string]string)
 			}
-			
-			// Validation of the value
-			if mark_awarded > part_max {
-				validation[ExamNo][partname] = "max mark is "+strconv.Itoa(part_max)	
+			if _, problem := validateMark(entry.Value, part_max, marks_granularity[partnum]); problem != "" {
+				moderation_validation[ExamNo][partname] = problem
 			}
 			
 			moderation_details[ExamNo][partname] = append(moderation_details[ExamNo][partname], entry.Value)
@@ -343,12 +525,43 @@ func ValidateMarking(form_values []FormValues, parts []*PaperStructure, outputCS
 		
 	}
 	
+	// Each script's moderated marks take the place of the original marks for that script only
+	script_marks_by_part := make(map[string]map[string][]string) // script_marks_by_part[ExamNo][part] = [4] - after moderation
+	for ExamNo, marks_by_part := range mark_details {
+		script_marks_by_part[ExamNo] = applyModeration(marks_by_part, moderation_details[ExamNo], validation[ExamNo], moderation_validation[ExamNo])
+		for pname, values := range script_marks_by_part[ExamNo] {
+			for _, value := range values {
+				if _, err := parseMark(value); err == nil {
+					marks_awarded_count[pname]++
+				}
+			}
+		}
+	}
+	
 	// Carry out further validation of the marks
 	// Also prepare the mark cells of the CSV
-	mark_summary := make(map[string]map[string]string) // mark_summary[ExamNo][part] = "4+5" or "4" or "2.5"
-	row_totals := make(map[string]int) // row_totals["B123456"] = 15
-	col_totals := make(map[string]int) // col_totals["1a"] = 250
-	for ExamNo, marks_by_part := range mark_details {
+	mark_summary := make(map[string]map[string]string) // mark_summary[ExamNo]["Unmarked"] = "Unmarked" for scripts with no marks yet
+	row_totals := make(map[string]float64) // row_totals["B123456"] = 15.5
+	script_marks := make(map[string]map[string]float64) // script_marks["B123456"]["1a"] = 2.5
+	adjusted_totals := make(map[string]float64) // adjusted_totals["B123456"] = 16.5 - with the scaling's part adjustments, ready to be scaled
+	scaled_marks := make(map[string]map[string]float64) // scaled_marks["B123456"]["3b"] = 4 - the parts adjusted by the scaling
+	dropped_parts := make(map[string][]string) // dropped_parts["B123456"] = ["3a", "3b"] - optional parts not counted in the total
+	warnings := make(map[string]map[string]string) // warnings["B123456"]["choice B"] = "answered 3 questions, best 2 counted (Q3 dropped)"
+	col_totals := make(map[string]float64) // col_totals["1a"] = 250
+	col_counts := make(map[string]int) // col_counts["1a"] = 50 - number of scripts with marks in this column
+	col_values := make(map[string][]float64) // col_values["1a"] = [2, 4, 3.5] - the marks for each script, for the statistics
+	
+	// optional questions are averaged over the scripts that answered them
+	optional_column := make(map[string]bool)
+	for pname := range optional_part {
+		optional_column[pname] = true
+	}
+	for _, group := range subtotals {
+		if group.Level != "section" && optional_part[group.Parts[0]] {
+			optional_column[group.Name] = true
+		}
+	}
+	for ExamNo, marks_by_part := range script_marks_by_part {
 		
 		// Prepare the nested maps to receive values
 		if validation[ExamNo] == nil {
@@ -372,70 +585,119 @@ func ValidateMarking(form_values []FormValues, parts []*PaperStructure, outputCS
 			continue
 		}
 		
-		// Further validation of each part
 		for _, pname := range part_name {
 		
-			// Overwrite with moderation if it exists
-			if moderation_details[ExamNo][pname] != nil {
-				marks_by_part[pname] = moderation_details[ExamNo][pname]
-			}
-		
 			// Represent a lack of marks by an empty list
 			if marks_by_part[pname] == nil {
 				marks_by_part[pname] = []string{}
 			}
+		}
+		
+		// Marks out of range are flagged above, and left out of the totals
+		counted := countedMarks(marks_by_part, part_to_marks)
+		
+		// Work out which optional questions were answered, and which count towards the total
+		dropped_part := make(map[string]bool)
+		attempted_question := make(map[string]bool)
+		for _, choice := range choices {
+			result := choice.score(counted)
+			for _, qname := range result.Attempted {
+				attempted_question[choice.Name+"/"+qname] = true
+			}
+			for _, qname := range result.Dropped {
+				for _, qn := range choice.Questions {
+					if qn.Name == qname {
+						for _, pname := range qn.Parts {
+							dropped_part[pname] = true
+						}
+					}
+				}
+			}
+			// the best answers are counted anyway, so this is a note for the markers rather than a problem with the marking
+			if problem := choice.problem(result); problem != "" {
+				if warnings[ExamNo] == nil {
+					warnings[ExamNo] = make(map[string]string)
+				}
+				warnings[ExamNo]["choice "+choice.Name] = problem
+			}
+		}
+		
+		for _, pname := range part_name {
+			if dropped_part[pname] {
+				dropped_parts[ExamNo] = append(dropped_parts[ExamNo], pname)
+			}
+		}
+		sort.Strings(dropped_parts[ExamNo])
+		
+		// Further validation of each part
+		script_marks[ExamNo] = make(map[string]float64)
+		for _, pname := range part_name {
 		
 			// If marks have been awarded to at least one student for this part, check that this student has a mark too
-			if  _, ok := marks_awarded[pname]; ok {
+			// (unless it is part of an optional question that this student did not answer)
+			if marks_awarded_count[pname] > 0 {
 				if len(marks_by_part[pname]) == 0 {
 					validation[ExamNo][pname] = "not marked"
 				}
 			}
+			for _, choice := range choices {
+				if qn, ok := choice.questionOf(pname); ok && !attempted_question[choice.Name+"/"+qn.Name] {
+					delete(validation[ExamNo], pname)
+				}
+			}
 		
 			// Warn if marks are awarded on more than 1 occasion
 			if len(marks_by_part[pname]) > 1 {
 				validation[ExamNo][pname] = "multiple marks"
 			}
 			
-			// Produce the entry that will appear in this cell of the table
-			mark_summary[ExamNo][pname] = strings.Join(marks_by_part[pname], " + ")
-			
-			// Contribute to the row/col totals
-			cell_value := sumOfMarks(marks_by_part[pname])
-			row_totals[ExamNo] = row_totals[ExamNo] + cell_value
+			// Contribute to the col totals (the row total is worked out below)
+			cell_value := sumOfMarks(counted[pname])
+			script_marks[ExamNo][pname] = cell_value
 			col_totals[pname] = col_totals[pname] + cell_value			
+			if len(marks_by_part[pname]) > 0 {
+				col_counts[pname]++
+			}
+			if len(marks_by_part[pname]) > 0 || !optional_column[pname] {
+				col_values[pname] = append(col_values[pname], cell_value)
+			}
 		
 		}
 		
-		// add the Total column
-		//mark_summary[ExamNo]["Total"] = strconv.Itoa(script_total[ExamNo])
-		mark_summary[ExamNo]["Total"] = strconv.Itoa(row_totals[ExamNo])
-		
-		// add the validation messages
-		part_validation := make([]string, 0, len(partnames))
-		for  _, pname := range part_name {
-			if  _, ok := validation[ExamNo][pname]; ok {
-				part_validation = append(part_validation, pname+": "+validation[ExamNo][pname])
+		// add the subtotal columns - section totals leave out any optional questions that were dropped
+		for _, group := range subtotals {
+			subtotal := 0.0
+			attempted := false
+			for _, pname := range group.Parts {
+				if group.Level == "section" && dropped_part[pname] {
+					continue
+				}
+				subtotal = subtotal + sumOfMarks(counted[pname])
+				attempted = attempted || len(marks_by_part[pname]) > 0
+			}
+			if attempted {
+				col_counts[group.Name]++
+			}
+			if attempted || !optional_column[group.Name] {
+				col_values[group.Name] = append(col_values[group.Name], subtotal)
 			}
+			script_marks[ExamNo][group.Name] = subtotal
+			col_totals[group.Name] = col_totals[group.Name] + subtotal
 		}
-		sort.Strings(part_validation)
-		mark_summary[ExamNo]["Validation"] = strings.Join(part_validation, "; ")
 		
+		// add the Total column
+		row_totals[ExamNo] = scriptTotal(counted, part_to_marks, choices, optional_part, opts.TotalRounding)
 		
-		// Unmarked Pages - add column to mark_summary
-		unmarked_pages := []int{}
-		// Determine unmarked pages
-		for pagenum, markings := range marks_on_page[ExamNo] {
-			if markings == 0 {
-				unmarked_pages = append(unmarked_pages, pagenum)
-			}
+		// the scaling's part adjustments are added up in the same way, so that an adjusted optional question can change
+		// which answers count
+		if opts.Scaling != nil {
+			adjusted, adjusted_marks := opts.Scaling.adjustMarks(counted, part_to_marks)
+			adjusted_totals[ExamNo] = scriptTotal(adjusted, part_to_marks, choices, optional_part, opts.TotalRounding)
+			scaled_marks[ExamNo] = adjusted_marks
 		}
-		sort.Ints(unmarked_pages)
-		mark_summary[ExamNo]["Unmarked Pages"] = strings.Trim(strings.Join(strings.Fields(fmt.Sprint(unmarked_pages)), ", "), "[]") // https://stackoverflow.com/a/37533144
 		
-		// Bad Pages - add column to mark_summary
+		// Bad Pages are listed in order
 		sort.Ints(bad_pages[ExamNo])
-		mark_summary[ExamNo]["Bad Pages"] = strings.Trim(strings.Join(strings.Fields(fmt.Sprint(bad_pages[ExamNo])), ", "), "[]") // https://stackoverflow.com/a/37533144
 		
 		//PrettyPrintStruct(mark_summary[ExamNo])
 		//PrettyPrintStruct(validation[ExamNo])
@@ -443,150 +705,145 @@ func ValidateMarking(form_values []FormValues, parts []*PaperStructure, outputCS
 	}
 	
 	/*=======================================================================
-	|   Produce the CSV output
+	|   Produce the summary
 	=======================================================================*/
 	
-	//fmt.Println("MARK SUMMARY")
-	//PrettyPrintStruct(mark_summary)
-	//PrettyPrintStruct(part_name)
-	fmt.Printf("\n\nWriting summary for %d scripts", len(mark_summary))
-	
-	file, err := os.OpenFile(outputCSV, os.O_RDWR|os.O_CREATE, os.ModePerm)
-	check(err)
-	defer file.Close()
-	w := csv.NewWriter(file)
-	
-	// Basic info about the marking
-	err = w.Write([]string{"Exam: ",coursecode})
-	err = w.Write([]string{"Marker: ",keysAsCommaString(markers)})
-	err = w.Write([]string{""})
-	
-	// Prepare the headers
-	csv_headers := append([]string{"Exam Number"}, partnames...)
-	csv_headers = append(csv_headers, []string{"Total", "Validation", "Unmarked Pages", "Bad Pages"}...)
-
-	//
-	// Write the header and stats summary rows
-	err = w.Write(append([]string{""}, append(partnames, "Total")...))
-	check(err)
-	
-	// Add a row showing what each question is marked out of
-	row_outof := []string{"out of:"}
-	for _, val := range csv_headers {
-		if outof, ok := part_to_marks[val]; ok {
-			row_outof = append(row_outof, fmt.Sprintf("%v", outof))
-		}
-	}
-	paper_outof := 0
-	for _, part := range parts {
-		if part.Part != "" {
-			paper_outof = paper_outof + part.Marks
+	summary := &MarksSummary{Course: coursecode, OutOf: OutOf(parts)}
+	for marker := range markers {
+		if marker != "" {
+			summary.Markers = append(summary.Markers, marker)
 		}
 	}
-	row_outof = append(row_outof, fmt.Sprintf("%v", paper_outof))
-	err = w.Write(row_outof)
-	check(err)
+	sort.Strings(summary.Markers)
 	
-	// Add rows showing the item means
-	paper_mean := 0
+	// Work out the item means
+	paper_mean := 0.0
 	num_scripts := 0
 	for _, tot := range row_totals {
 		paper_mean = paper_mean + tot
 		num_scripts++
 	}
-	row_means := []string{"mean:"}
-	row_means_pc := []string{"mean (%):"}
-	for _, val := range partnames {
-		mean_string := ""
-		mean_string_pc := ""
-		if _, ok := col_totals[val]; ok {
-			if marks_awarded_count[val] > 0 { // protect from division by 0
-				mean_string = fmt.Sprintf("%.2f", float64(col_totals[val])/float64(num_scripts))
-				mean_string_pc = fmt.Sprintf("%.1f", (100/float64(part_to_marks[val]))*float64(col_totals[val])/float64(num_scripts))
+	// only show means for columns where some marks have been awarded
+	column_marked := make(map[string]bool)
+	for pname, count := range marks_awarded_count {
+		column_marked[pname] = count > 0
+	}
+	for _, group := range subtotals {
+		for _, pname := range group.Parts {
+			column_marked[group.Name] = column_marked[group.Name] || column_marked[pname]
+		}
+	}
+	column_summary := func(name string) ColumnSummary {
+		column := ColumnSummary{Name: name, OutOf: column_outof[name], Optional: optional_column[name]}
+		scripts := num_scripts
+		if optional_column[name] {
+			scripts = col_counts[name]
+		}
+		if _, ok := col_totals[name]; ok {
+			if column_marked[name] && column_outof[name] > 0 && scripts > 0 { // protect from division by 0
+				mean := col_totals[name]/float64(scripts)
+				mean_pc := (100/column_outof[name])*mean
+				column.Mean = &mean
+				column.MeanPercent = &mean_pc
+				column.Distribution = describe(col_values[name], column_outof[name])
 			}
 		}
-		row_means = append(row_means, mean_string)
-		row_means_pc = append(row_means_pc, mean_string_pc)
+		return column
 	}
-	if num_scripts > 0 {
-		row_means = append(row_means, fmt.Sprintf("%v", float64(paper_mean)/float64(num_scripts)))	
-		row_means_pc = append(row_means_pc, fmt.Sprintf("%v", (100/float64(paper_outof))*float64(paper_mean)/float64(num_scripts)))	
-	}
-	err = w.Write(row_means)
-	check(err)
-	err = w.Write(row_means_pc)
-	check(err)
-	
-	// Separate the Validation/Complete/Unmarked blocks and sort these lists of students by Exam Number
-	student_records_invalid := []string{}
-	student_records_valid := []string{}
-	student_records_unmarked := []string{}
-	for enum, _ := range mark_summary {
-		if (len(mark_summary[enum]["Validation"]) +
-			len(mark_summary[enum]["Unmarked Pages"])) >0 {
-			student_records_invalid = append(student_records_invalid, enum)
-		} else if mark_summary[enum]["Unmarked"] == "Unmarked" {
-			student_records_unmarked = append(student_records_unmarked, enum)
-		} else {
-			student_records_valid = append(student_records_valid, enum)		
+	for pnum, part := range parts {
+		if pname, ok := part_name[pnum]; ok {
+			column := column_summary(pname)
+			column.Granularity = part.Granularity
+			summary.Parts = append(summary.Parts, column)
 		}
 	}
-	sort.Strings(student_records_invalid)
-	sort.Strings(student_records_valid)
-	sort.Strings(student_records_unmarked)
-
-	// Print each row for the invalid records - range over the csv_headers to look up the correct value for each column
-	err = w.Write([]string{""}) // blank row
-	err = w.Write([]string{"Validation problems ("+strconv.Itoa(len(student_records_invalid))+" scripts):"})
-	check(err)
-	err = w.Write(csv_headers)
-	check(err)
-	for _, ExamNo := range student_records_invalid {
-		record := []string{fmt.Sprintf("%v", ExamNo)}
-		for _, val := range csv_headers {
-			if val == "Exam Number" { continue }
-			record = append(record, fmt.Sprintf("%v", mark_summary[ExamNo][val]))
+	sort.SliceStable(summary.Parts, func(i, j int) bool { return summary.Parts[i].Name < summary.Parts[j].Name })
+	for _, group := range subtotals {
+		column := column_summary(group.Name)
+		column.Level = group.Level
+		column.Parts = group.Parts
+		summary.Subtotals = append(summary.Subtotals, column)
+	}
+	if num_scripts > 0 {
+		mean := paper_mean/float64(num_scripts)
+		mean_pc := (100/summary.OutOf)*mean
+		summary.Statistics.Mean = &mean
+		summary.Statistics.MeanPercent = &mean_pc
+		
+		totals := make([]float64, 0, num_scripts)
+		for _, tot := range row_totals {
+			totals = append(totals, tot)
 		}
-		err := w.Write(record)
-		check(err)
+		summary.Statistics.Distribution = describe(totals, summary.OutOf)
+		summary.Statistics.Histogram = histogram(totals, summary.OutOf, opts.HistogramWidth)
 	}
-
 	
-	// Now do the valid ones
-	err = w.Write([]string{""}) // blank row
-	err = w.Write([]string{"Marking completed ("+strconv.Itoa(len(student_records_valid))+" scripts):"})
-	check(err)
-	err = w.Write(csv_headers)
-	check(err)
-	for _, ExamNo := range student_records_valid {
-		record := []string{fmt.Sprintf("%v", ExamNo)}
-		for _, val := range csv_headers {
-			if val == "Exam Number" { continue }
-			record = append(record, fmt.Sprintf("%v", mark_summary[ExamNo][val]))
+	// Separate the Validation/Complete/Unmarked scripts and sort them by Exam Number
+	for ExamNo := range mark_summary {
+		script := ScriptSummary{ExamNumber: ExamNo}
+		if len(script_markers[ExamNo]) > 0 {
+			script.Markers = sortedKeys(script_markers[ExamNo])
+		}
+		if mark_summary[ExamNo]["Unmarked"] == "Unmarked" {
+			script.Status = StatusUnmarked
+		} else {
+			script.MarksEntered = script_marks_by_part[ExamNo]
+			script.Marks = script_marks[ExamNo]
+			script.Total = row_totals[ExamNo]
+			script.BadPages = bad_pages[ExamNo]
+			script.DroppedParts = dropped_parts[ExamNo]
+			script.ScaledMarks = scaled_marks[ExamNo]
+			script.Warnings = warnings[ExamNo]
+			script.Validation = make(map[string]string)
+			for pname, problem := range validation[ExamNo] {
+				if _, ok := part_to_marks[pname]; ok {
+					script.Validation[pname] = problem
+				}
+			}
+			for pagenum, markings := range marks_on_page[ExamNo] {
+				if markings == 0 {
+					script.UnmarkedPages = append(script.UnmarkedPages, pagenum)
+				}
+			}
+			sort.Ints(script.UnmarkedPages)
+			if len(script.Validation) + len(script.UnmarkedPages) > 0 {
+				script.Status = StatusInvalid
+			} else {
+				script.Status = StatusComplete
+			}
+		}
+		summary.Scripts = append(summary.Scripts, script)
+		switch script.Status {
+		case StatusInvalid:
+			summary.Statistics.Invalid++
+		case StatusComplete:
+			summary.Statistics.Complete++
+		case StatusUnmarked:
+			summary.Statistics.Unmarked++
 		}
-		err := w.Write(record)
-		check(err)
 	}
+	sort.Slice(summary.Scripts, func(i, j int) bool { return summary.Scripts[i].ExamNumber < summary.Scripts[j].ExamNumber })
+	summary.Statistics.Scripts = len(summary.Scripts)
 	
-	// Now do the unmarked ones
-	err = w.Write([]string{""}) // blank row
-	err = w.Write([]string{"Yet to be marked ("+strconv.Itoa(len(student_records_unmarked))+" scripts):"})
-	check(err)
-	for _, ExamNo := range student_records_unmarked {
-		record := []string{fmt.Sprintf("%v", ExamNo)}
-		err := w.Write(record)
-		check(err)
-	}
-
-	w.Flush()
+	applyScaling(summary, opts.Scaling, adjusted_totals)
+	applyGrades(summary, opts.Grades)
 	
-	return nil
+	return summary
 }
 
 func sliceToCommaString(input_slice []string) string {
 	return strings.Trim(strings.Join(strings.Fields(fmt.Sprint(input_slice)), ", "), "[]") // https://stackoverflow.com/a/37533144
 }
 
+func contains(list []string, item string) bool {
+	for _, entry := range list {
+		if entry == item {
+			return true
+		}
+	}
+	return false
+}
+
 func keysAsCommaString(input_map map[string]bool) string {
 	keys := make([]string, 0, len(input_map))
     for k, _ := range input_map {
@@ -595,55 +852,19 @@ func keysAsCommaString(input_map map[string]bool) string {
 	return sliceToCommaString(keys)
 }
 
-func sumOfMarks(mark_slice []string) int {
-	sum := 0
+func sumOfMarks(mark_slice []string) float64 {
+	sum := 0.0
     for _, mark := range mark_slice {
-        mark_int, err := strconv.Atoi(mark)
+        mark_val, err := parseMark(mark)
 		if err != nil {
 			fmt.Println("Error adding up marks:",mark_slice)
 			return 0
 		}
-		sum = sum + mark_int
+		sum = sum + mark_val
     }
 	return sum
 }
 
-func extractMarkerInitials(pdf_text map[int]string) string {
-	// TODO - this could check *all* pages to make sure the initials are consistent,
-	// but let's be lazy and just use the first page
-	raw_string_p1 := pdf_text[0]
-	initials := ""
-	
-	// initials appear as the second line of text https://regex101.com/r/9GjHTM/9
-	findinitials, _ := regexp.Compile(".*\n([a-zA-Z]+)\n")
-	matches := findinitials.FindStringSubmatch(raw_string_p1)
-	if len(matches)>0 {
-		initials = matches[1]
-	}
-		
-	return initials
-}
-
-func extractCourseCode(pdf_text map[int]string) string {
-	// TODO - this could check *all* pages for consistency
-	// but let's be lazy and just use the first page
-	raw_string_p1 := pdf_text[0]
-	
-	// course code is the first word of text https://regex101.com/r/9GjHTM/10
-	findinitials, _ := regexp.Compile("([a-zA-Z0-9]+) ")
-	return findinitials.FindStringSubmatch(raw_string_p1)[1]
-}
-
-func extractExamNumber(pdf_text map[int]string) string {
-	// TODO - this could check *all* pages for consistency
-	// but let's be lazy and just use the first page
-	raw_string_p1 := pdf_text[0]
-	
-	// exam number is the last word on the first line https://regex101.com/r/9GjHTM/11
-	findexamno, _ := regexp.Compile(" ([a-zA-Z0-9]+)\n")
-	return findexamno.FindStringSubmatch(raw_string_p1)[1]
-}
-
 func hasContent(str string) bool {
 	return strings.Compare(str, "") != 0
 }
@@ -704,6 +925,46 @@ func insertCheckReport(scan *ScanResult, fields map[string]string) {
 
 
 
+// ReadCheckReportsInDirectory collects the scan/heading/filename checks from every PDF in formsPath
+func ReadCheckReportsInDirectory(formsPath string) ([]ScanResult, error) {
+
+	results := []ScanResult{}
+
+	err := filepath.Walk(formsPath, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.IsDir() || filepath.Ext(f.Name()) != ".pdf" {
+			return nil
+		}
+		script, err := OpenScript(path)
+		if err != nil {
+			fmt.Println(" - ", err)
+			return nil
+		}
+		field_data := script.Fields()
+		script.Close()
+
+		// the check fields may be repeated on each page, so match on the base name of the field
+		fields := make(map[string]string)
+		for key, val := range field_data {
+			if strings.Contains(key, "page-") {
+				_, key = whatPageIsThisFrom(key)
+			}
+			if hasContent(val) || fields[key] == "" {
+				fields[key] = val
+			}
+		}
+
+		scan := ScanResult{InputFile: path}
+		insertCheckReport(&scan, fields)
+		results = append(results, scan)
+		return nil
+	})
+
+	return results, err
+}
+
 func readIngestReport(inputPath string) ([]*parselearn.Submission, error) {
 	subs := []*parselearn.Submission{}
 	f, err := os.Open(inputPath)
@@ -740,6 +1001,9 @@ func whatPageIsThisFrom(key string) (int, string) {
 	// Pick out page number and basekey https://regex101.com/r/vGyDbg/1
 	parse_field_name, _ := regexp.Compile(".*page-([0-9]+)-(.*)")
 	parsed_key := parse_field_name.FindStringSubmatch(key)
+	if len(parsed_key) < 3 {
+		return -1, ""
+	}
 	parsed_pageno, err := strconv.Atoi(parsed_key[1])
 	if err != nil {
 		return -1, ""
@@ -760,136 +1024,15 @@ func whoMarkedThisPage(key string) (string) {
 
 }
 
-func printPdfFieldData(inputPath, targetFieldName string) error {
-	f, err := os.Open(inputPath)
-	if err != nil {
-		return err
-	}
-	fmt.Printf("Input file: %s\n", inputPath)
-
-	defer f.Close()
-
-	pdfReader, err := pdf.NewPdfReader(f)
+// PrintPdfFieldData prints the value of targetFieldName, or of every field if targetFieldName is empty
+func PrintPdfFieldData(inputPath, targetFieldName string) error {
+	script, err := OpenScript(inputPath)
 	if err != nil {
 		return err
 	}
+	defer script.Close()
 
-	acroForm := pdfReader.AcroForm
-	if acroForm == nil {
-		fmt.Printf(" No formdata present\n")
-		return nil
-	}
-
-	match := false
-	fields := acroForm.AllFields()
-	for _, field := range fields {
-		fullname, err := field.FullName()
-		if err != nil {
-			return err
-		}
-		if fullname == targetFieldName || targetFieldName == "" {
-			match = true
-			if field.V != nil {
-				fmt.Printf("Field '%s': '%v' (%T)\n", fullname, field.V, field.V)
-			} else {
-				fmt.Printf("Field '%s': not filled\n", fullname)
-			}
-		}
-	}
-
-	if !match {
-		return errors.New("field not found")
-	}
-	return nil
-}
-
-func mapPdfFieldData(inputPath string) (map[string]string, error) {
-
-	textfields := make(map[string]string)
-
-	f, err := os.Open(inputPath)
-	if err != nil {
-		return textfields, errors.New(fmt.Sprintf("Problem opening file %s", inputPath))
-	}
-	defer f.Close()
-
-	pdfReader, err := pdf.NewPdfReader(f)
-	if err != nil {
-		return textfields, errors.New(fmt.Sprintf("Problem creating reader %s", inputPath))
-	}
-
-	acroForm := pdfReader.AcroForm
-	if acroForm == nil {
-		return textfields, nil
-	}
-
-	fields := acroForm.AllFields()
-	for _, field := range fields {
-		fullname, err := field.FullName()
-		if err != nil {
-			continue
-		}
-
-		val := ""
-
-		if field.V != nil {
-			val = field.V.String()
-		}
-
-		textfields[fullname] = val
-
-	}
-
-	return textfields, nil
-}
-
-func getText(inputPath string, opt cmdOptions) (map[int]string, error) {
-
-	texts := make(map[int]string)
-
-	f, err := os.Open(inputPath)
-	if err != nil {
-		return texts, err
-	}
-	defer f.Close()
-
-	pdfReader, err := pdf.NewPdfReader(f)
-	if err != nil {
-		return texts, err
-	}
-
-	isEncrypted, err := pdfReader.IsEncrypted()
-	if err != nil {
-		return texts, err
-	}
-
-	// Try decrypting with an empty one.
-	if isEncrypted {
-		auth, err := pdfReader.Decrypt([]byte(opt.pdfPassword))
-		if err != nil {
-			return texts, err
-		}
-
-		if !auth {
-			return texts, errors.New("Unable to decrypt password protected file - need to specify pass to Decrypt")
-		}
-	}
-	for p, page := range pdfReader.PageList {
-
-		ex, err := extractor.New(page)
-
-		if err == nil {
-
-			text, err := ex.ExtractText()
-			if err == nil {
-				texts[p] = text
-			}
-
-		}
-	}
-
-	return texts, nil
-
+	return script.PrintFields(targetFieldName)
 }
 
 func PrettyPrintStruct(layout interface{}) error {
@@ -0,0 +1,70 @@
+package pdfextract
+
+import (
+	"testing"
+)
+
+func TestExtractHeader(t *testing.T) {
+
+	conv := DefaultConventions()
+	header := map[int]string{0: "MATH01234 B123456\nGK\nrest of the page"}
+
+	if course, err := conv.extractCourseCode(header); err != nil || course != "MATH01234" {
+		t.Errorf("extractCourseCode() = %q, %v", course, err)
+	}
+	if examno, err := conv.extractExamNumber(header); err != nil || examno != "B123456" {
+		t.Errorf("extractExamNumber() = %q, %v", examno, err)
+	}
+	if initials := conv.extractMarkerInitials(header); initials != "GK" {
+		t.Errorf("extractMarkerInitials() = %q", initials)
+	}
+
+	// a page without the expected header should give an error rather than a panic
+	blank := map[int]string{0: ""}
+	if _, err := conv.extractCourseCode(blank); err == nil {
+		t.Error("expected an error for a missing course code")
+	}
+	if _, err := conv.extractExamNumber(blank); err == nil {
+		t.Error("expected an error for a missing exam number")
+	}
+}
+
+func TestWhatPageIsThisFrom(t *testing.T) {
+
+	if page, field := whatPageIsThisFrom("marker_GK-page-003-qn-part-mark-2"); page != 4 || field != "qn-part-mark-2" {
+		t.Errorf("whatPageIsThisFrom() = %d, %q", page, field)
+	}
+	if page, _ := whatPageIsThisFrom("pagebreak"); page != -1 {
+		t.Errorf("whatPageIsThisFrom() = %d for a field without a page number", page)
+	}
+}
+
+func TestCourseConfig(t *testing.T) {
+
+	config := CourseConfig{
+		FilenamePatterns: []string{"(?P<exam>B[0-9]{6})-.*\\.pdf", "resit-(?P<exam>R[0-9]{5})\\.pdf"},
+		HeaderPatterns:   []string{"^(?P<course>[A-Z]+[0-9]+) - (?P<exam>R[0-9]{5})\n(?:Marker: (?P<marker>[A-Z]+))?"},
+	}
+	conv, err := config.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if examno, ok := conv.ExamNumberFromFilename("resit-R12345.pdf"); !ok || examno != "R12345" {
+		t.Errorf("ExamNumberFromFilename() = %q, %v", examno, ok)
+	}
+	if _, ok := conv.ExamNumberFromFilename("B12345-notes.pdf"); ok {
+		t.Error("expected a malformed filename")
+	}
+
+	header := map[int]string{0: "MATH08057 - R12345\nMarker: JS\n"}
+	examno, _ := conv.extractExamNumber(header)
+	course, _ := conv.extractCourseCode(header)
+	if examno != "R12345" || course != "MATH08057" || conv.extractMarkerInitials(header) != "JS" {
+		t.Errorf("got %q, %q, %q from the header", course, examno, conv.extractMarkerInitials(header))
+	}
+
+	if _, err := (CourseConfig{FilenamePatterns: []string{"B[0-9]+"}}).Compile(); err == nil {
+		t.Error("expected an error for a filename pattern without an exam group")
+	}
+}
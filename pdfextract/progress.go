@@ -0,0 +1,130 @@
+package pdfextract
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gocarina/gocsv"
+)
+
+// MarkerProgress shows how far a marker has got through the pages they have been given
+type MarkerProgress struct {
+	Marker           string `csv:"Marker" json:"marker"`
+	Scripts          int    `csv:"Scripts" json:"scripts"` // scripts with at least one page for this marker
+	FullyMarked      int    `csv:"FullyMarked" json:"fully_marked"`
+	PartiallyMarked  int    `csv:"PartiallyMarked" json:"partially_marked"`
+	Untouched        int    `csv:"Untouched" json:"untouched"`
+	PagesSeen        int    `csv:"PagesSeen" json:"pages_seen"`
+	Pages            int    `csv:"Pages" json:"pages"`
+	PartsOutstanding int    `csv:"PartsOutstanding" json:"parts_outstanding"` // unmarked parts on this marker's pages, leaving out optional questions
+}
+
+// scriptCopy is one marker's pages of a script
+type scriptCopy struct {
+	ExamNo string
+	Marker string
+}
+
+// MarkerProgressReport works out each marker's progress. Each page belongs to the marker named on it (or in the
+// script header), and counts as seen once it has been ticked as seen or bad, or has a mark on it.
+func MarkerProgressReport(form_values []FormValues, parts []*PaperStructure) []MarkerProgress {
+
+	choices := ChoiceGroups(parts)
+	optional_part := make(map[int]bool)
+	for pnum, part := range parts {
+		for _, choice := range choices {
+			if _, ok := choice.questionOf(part.Label()); ok {
+				optional_part[pnum] = true
+			}
+		}
+	}
+
+	// each marker can have their own copy of a script, so the pages are kept apart by marker as well as exam number
+	pages := make(map[scriptCopy]map[int]bool)          // pages[{"B123456", "GK"}][page] = true
+	page_seen := make(map[scriptCopy]map[int]bool)      // page_seen[{"B123456", "GK"}][page] = true
+	parts_on_page := make(map[scriptCopy]map[int][]int) // parts_on_page[{"B123456", "GK"}][page] = [0, 1]
+	part_marked := make(map[scriptCopy]map[int]bool)    // part_marked[{"B123456", "GK"}][0] = true
+	for _, entry := range form_values {
+		page, field_name := whatPageIsThisFrom(entry.Field)
+		if page < 0 {
+			continue
+		}
+		script := scriptCopy{ExamNo: entry.ExamNumber, Marker: entry.Marker}
+		if pages[script] == nil {
+			pages[script] = make(map[int]bool)
+			page_seen[script] = make(map[int]bool)
+			parts_on_page[script] = make(map[int][]int)
+			part_marked[script] = make(map[int]bool)
+		}
+		pages[script][page] = true
+		has_content := hasContent(entry.Value)
+		if (field_name == "page-seen" || field_name == "page-bad") && has_content {
+			page_seen[script][page] = true
+		}
+		if partnum, moderation, ok := markField(field_name); ok && partnum < len(parts) {
+			if !moderation {
+				parts_on_page[script][page] = append(parts_on_page[script][page], partnum)
+			}
+			if has_content {
+				page_seen[script][page] = true
+				part_marked[script][partnum] = true
+			}
+		}
+	}
+
+	progress := make(map[string]*MarkerProgress)
+	for script, script_pages := range pages {
+		marker := script.Marker
+		if marker == "" {
+			marker = "unknown"
+		}
+		if progress[marker] == nil {
+			progress[marker] = &MarkerProgress{Marker: marker}
+		}
+		p := progress[marker]
+		p.Scripts++
+		p.Pages = p.Pages + len(script_pages)
+
+		seen := 0
+		outstanding := make(map[int]bool)
+		for page := range script_pages {
+			if page_seen[script][page] {
+				seen++
+			}
+			for _, partnum := range parts_on_page[script][page] {
+				if !part_marked[script][partnum] && !optional_part[partnum] {
+					outstanding[partnum] = true
+				}
+			}
+		}
+		p.PagesSeen = p.PagesSeen + seen
+		p.PartsOutstanding = p.PartsOutstanding + len(outstanding)
+
+		switch {
+		case seen == 0:
+			p.Untouched++
+		case seen == len(script_pages):
+			p.FullyMarked++
+		default:
+			p.PartiallyMarked++
+		}
+	}
+
+	report := make([]MarkerProgress, 0, len(progress))
+	for _, p := range progress {
+		report = append(report, *p)
+	}
+	sort.Slice(report, func(i, j int) bool { return strings.ToLower(report[i].Marker) < strings.ToLower(report[j].Marker) })
+	return report
+}
+
+// WriteMarkerProgressCSV saves the progress of each marker
+func WriteMarkerProgressCSV(report []MarkerProgress, outputCSV string) error {
+	file, err := os.OpenFile(outputCSV, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gocsv.MarshalFile(&report, file)
+}
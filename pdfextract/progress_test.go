@@ -0,0 +1,53 @@
+package pdfextract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarkerProgressReport(t *testing.T) {
+
+	form_values := []FormValues{
+		// GK has finished B000001, and started B000002
+		seenEntry("B000001", "GK", 1),
+		markEntry("B000001", "GK", 1, 0, "3"),
+		markEntry("B000001", "GK", 1, 1, "6"),
+		markEntry("B000001", "GK", 2, 2, "7"),
+		markEntry("B000002", "GK", 1, 0, "4"),
+		markEntry("B000002", "GK", 1, 1, ""),
+		markEntry("B000002", "GK", 2, 2, ""),
+		// JS has page 2 of B000003, and has not started
+		markEntry("B000003", "GK", 1, 0, "1"),
+		markEntry("B000003", "GK", 1, 1, "1"),
+		markEntry("B000003", "JS", 2, 2, ""),
+	}
+
+	want := []MarkerProgress{
+		{Marker: "GK", Scripts: 3, FullyMarked: 2, PartiallyMarked: 1, PagesSeen: 4, Pages: 5, PartsOutstanding: 2},
+		{Marker: "JS", Scripts: 1, Untouched: 1, Pages: 1, PartsOutstanding: 1},
+	}
+	if got := MarkerProgressReport(form_values, testParts); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestMarkerProgressCopies(t *testing.T) {
+
+	// GK and JS each have their own copy of B000001, with the same pages
+	form_values := []FormValues{
+		markEntry("B000001", "GK", 1, 0, "3"),
+		markEntry("B000001", "GK", 1, 1, "6"),
+		markEntry("B000001", "GK", 2, 2, "7"),
+		markEntry("B000001", "JS", 1, 0, "4"),
+		markEntry("B000001", "JS", 1, 1, ""),
+		markEntry("B000001", "JS", 2, 2, ""),
+	}
+
+	want := []MarkerProgress{
+		{Marker: "GK", Scripts: 1, FullyMarked: 1, PagesSeen: 2, Pages: 2},
+		{Marker: "JS", Scripts: 1, PartiallyMarked: 1, PagesSeen: 1, Pages: 2, PartsOutstanding: 2},
+	}
+	if got := MarkerProgressReport(form_values, testParts); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v", got)
+	}
+}
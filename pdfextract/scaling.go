@@ -0,0 +1,231 @@
+package pdfextract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// How the script totals are scaled
+const (
+	ScaleNone      = ""          // only the part adjustments are applied
+	ScaleLinear    = "linear"    // scaled = multiply * total + add
+	ScalePiecewise = "piecewise" // straight lines between points, given as percentages
+)
+
+// Scaling approved by the exam board, read from a json file, e.g.
+//
+//	{
+//		"method": "piecewise",
+//		"points": [[0, 0], [35, 40], [100, 100]],
+//		"parts": {"3b": {"add": 1}},
+//		"approved_by": "MATH01234 exam board",
+//		"approved_on": "2021-06-14",
+//		"reason": "question 3b was harder than intended"
+//	}
+//
+// The part adjustments are applied to each part's mark first (keeping it between 0 and the marks available), and the
+// adjusted parts are totalled like the raw marks - with the best answers to optional questions, and the same rounding.
+// Then the adjusted total is scaled with the method. The scaled total is kept between 0 and the marks available.
+type Scaling struct {
+	Method     string                `json:"method"`
+	Multiply   *float64              `json:"multiply,omitempty"` // linear (default 1, so 0 can be given)
+	Add        float64               `json:"add,omitempty"`      // linear
+	Points     [][2]float64          `json:"points,omitempty"`   // piecewise: [raw %, scaled %], from raw 0% to raw 100%
+	Parts      map[string]Adjustment `json:"parts,omitempty"`    // adjustments to the marks for individual parts
+	ApprovedBy string                `json:"approved_by"`
+	ApprovedOn string                `json:"approved_on"`
+	Reason     string                `json:"reason,omitempty"`
+
+	file string // where the scaling was read from, for the audit record
+}
+
+// An adjustment to the marks for one part: adjusted = multiply * mark + add
+type Adjustment struct {
+	Multiply *float64 `json:"multiply,omitempty"` // default 1, so 0 can be given
+	Add      float64  `json:"add,omitempty"`
+}
+
+// LoadScaling reads and checks a scaling file
+func LoadScaling(path string) (*Scaling, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scaling := &Scaling{}
+	if err := json.Unmarshal(data, scaling); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := scaling.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	scaling.file = path
+	return scaling, nil
+}
+
+func (scaling *Scaling) check() error {
+	if scaling.ApprovedBy == "" || scaling.ApprovedOn == "" {
+		return errors.New("scaling needs approved_by and approved_on")
+	}
+	switch scaling.Method {
+	case ScaleNone:
+		if len(scaling.Parts) == 0 {
+			return errors.New("no scaling method or part adjustments")
+		}
+	case ScaleLinear:
+	case ScalePiecewise:
+		points := scaling.Points
+		if len(points) < 2 || points[0][0] != 0 || points[len(points)-1][0] != 100 {
+			return errors.New("piecewise scaling needs points from raw 0% to raw 100%")
+		}
+		for i := 1; i < len(points); i++ {
+			if points[i][0] <= points[i-1][0] {
+				return errors.New("piecewise scaling points must be in order of raw percentage")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown scaling method %q (use linear or piecewise)", scaling.Method)
+	}
+	return nil
+}
+
+// CheckParts makes sure the part adjustments are for parts of the paper
+func (scaling *Scaling) CheckParts(parts []*PaperStructure) error {
+	labels := make(map[string]bool)
+	for _, part := range parts {
+		labels[part.Label()] = true
+	}
+	for pname := range scaling.Parts {
+		if !labels[pname] {
+			return fmt.Errorf("scaling adjusts part %s, which is not in the parts csv", pname)
+		}
+	}
+	return nil
+}
+
+// multiplier is the multiply given, or 1 if there isn't one
+func multiplier(multiply *float64) float64 {
+	if multiply == nil {
+		return 1
+	}
+	return *multiply
+}
+
+func (adjustment Adjustment) apply(mark float64, out_of float64) float64 {
+	return math.Max(0, math.Min(out_of, multiplier(adjustment.Multiply)*mark+adjustment.Add))
+}
+
+// adjustMarks applies the part adjustments to a script's counted marks, giving the marks with each adjusted part in
+// place of the original, and the adjusted marks on their own for the summary
+func (scaling *Scaling) adjustMarks(counted map[string][]string, part_max map[string]float64) (map[string][]string, map[string]float64) {
+	adjusted := make(map[string][]string)
+	for pname, values := range counted {
+		adjusted[pname] = values
+	}
+	var scaled_marks map[string]float64
+	for pname, adjustment := range scaling.Parts {
+		if len(counted[pname]) == 0 {
+			continue
+		}
+		mark := adjustment.apply(sumOfMarks(counted[pname]), part_max[pname])
+		adjusted[pname] = []string{strconv.FormatFloat(mark, 'f', -1, 64)}
+		if scaled_marks == nil {
+			scaled_marks = make(map[string]float64)
+		}
+		scaled_marks[pname] = mark
+	}
+	return adjusted, scaled_marks
+}
+
+// scaleTotal scales an (adjusted) total out of out_of
+func (scaling *Scaling) scaleTotal(total float64, out_of float64) float64 {
+	scaled := total
+	switch scaling.Method {
+	case ScaleLinear:
+		scaled = multiplier(scaling.Multiply)*total + scaling.Add
+	case ScalePiecewise:
+		percentage := 100 * total / out_of
+		points := scaling.Points
+		for i := 1; i < len(points); i++ {
+			if percentage <= points[i][0] || i == len(points)-1 {
+				from, to := points[i-1], points[i]
+				scaled_pc := from[1] + (percentage-from[0])*(to[1]-from[1])/(to[0]-from[0])
+				scaled = scaled_pc * out_of / 100
+				break
+			}
+		}
+	}
+	return math.Max(0, math.Min(out_of, scaled))
+}
+
+// applyScaling scales the adjusted total of each marked script, keeping the raw marks and total as they are
+func applyScaling(summary *MarksSummary, scaling *Scaling, adjusted_totals map[string]float64) {
+	if scaling == nil || summary.OutOf <= 0 {
+		return
+	}
+	summary.Scaling = scaling
+	for i := range summary.Scripts {
+		script := &summary.Scripts[i]
+		if script.Status == StatusUnmarked {
+			continue
+		}
+		scaled := roundToStep(scaling.scaleTotal(adjusted_totals[script.ExamNumber], summary.OutOf), 0.01)
+		script.ScaledTotal = &scaled
+	}
+}
+
+// ScalingAudit is the record of the scaling that was applied, and what it did to each script
+type ScalingAudit struct {
+	AppliedAt   string         `json:"applied_at"`
+	ScalingFile string         `json:"scaling_file"`
+	Scaling     *Scaling       `json:"scaling"`
+	Scripts     int            `json:"scripts"`
+	RawMean     float64        `json:"raw_mean"`
+	ScaledMean  float64        `json:"scaled_mean"`
+	Changes     []ScaledScript `json:"changes"`
+}
+
+type ScaledScript struct {
+	ExamNumber  string             `json:"exam_number"`
+	Total       float64            `json:"total"`
+	ScaledMarks map[string]float64 `json:"scaled_marks,omitempty"`
+	ScaledTotal float64            `json:"scaled_total"`
+}
+
+// WriteScalingAudit records the scaling parameters, who approved them, and the raw and scaled totals of each script
+func WriteScalingAudit(summary *MarksSummary, outputJSON string) error {
+	if summary.Scaling == nil {
+		return errors.New("no scaling has been applied")
+	}
+	audit := ScalingAudit{
+		AppliedAt:   time.Now().Format(time.RFC3339),
+		ScalingFile: summary.Scaling.file,
+		Scaling:     summary.Scaling,
+		Changes:     []ScaledScript{},
+	}
+	raw, scaled := []float64{}, []float64{}
+	for _, script := range summary.Scripts {
+		if script.ScaledTotal == nil {
+			continue
+		}
+		raw = append(raw, script.Total)
+		scaled = append(scaled, *script.ScaledTotal)
+		audit.Changes = append(audit.Changes, ScaledScript{script.ExamNumber, script.Total, script.ScaledMarks, *script.ScaledTotal})
+	}
+	sort.Slice(audit.Changes, func(i, j int) bool { return audit.Changes[i].ExamNumber < audit.Changes[j].ExamNumber })
+	audit.Scripts = len(raw)
+	audit.RawMean = mean(raw)
+	audit.ScaledMean = mean(scaled)
+
+	json_data, err := json.MarshalIndent(audit, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(outputJSON, json_data, os.ModePerm)
+}
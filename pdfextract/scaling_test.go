@@ -0,0 +1,106 @@
+package pdfextract
+
+import (
+	"testing"
+)
+
+func float(x float64) *float64 {
+	return &x
+}
+
+func TestScaleTotal(t *testing.T) {
+
+	linear := &Scaling{Method: ScaleLinear, Multiply: float(1.1), Add: 2, ApprovedBy: "board", ApprovedOn: "2021-06-14"}
+	piecewise := &Scaling{Method: ScalePiecewise, Points: [][2]float64{{0, 0}, {35, 40}, {100, 100}}, ApprovedBy: "board", ApprovedOn: "2021-06-14"}
+	for _, scaling := range []*Scaling{linear, piecewise} {
+		if err := scaling.check(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		scaling *Scaling
+		total   float64
+		want    float64
+	}{
+		{linear, 10, 13},
+		{linear, 19, 20}, // no more than the marks available
+		{piecewise, 7, 8},
+		{piecewise, 0, 0},
+		{piecewise, 20, 20},
+	}
+	for _, test := range tests {
+		if got := test.scaling.scaleTotal(test.total, 20); got != test.want {
+			t.Errorf("%s scaling of %v = %v, want %v", test.scaling.Method, test.total, got, test.want)
+		}
+	}
+
+	for _, bad := range []*Scaling{
+		{Method: ScaleLinear},
+		{Method: ScalePiecewise, Points: [][2]float64{{0, 0}, {50, 60}}, ApprovedBy: "board", ApprovedOn: "today"},
+		{Method: "curve", ApprovedBy: "board", ApprovedOn: "today"},
+		{ApprovedBy: "board", ApprovedOn: "today"},
+	} {
+		if err := bad.check(); err == nil {
+			t.Errorf("expected an error for %+v", bad)
+		}
+	}
+}
+
+func TestSummaryScaling(t *testing.T) {
+
+	form_values := []FormValues{
+		markEntry("B000001", "GK", 1, 0, "2"),
+		markEntry("B000001", "GK", 1, 1, "6"),
+		markEntry("B000001", "GK", 2, 2, "2"),
+		blankEntry("B000002", 1),
+	}
+	scaling := &Scaling{Method: ScaleLinear, Multiply: float(1), Add: 1, Parts: map[string]Adjustment{"2": {Add: 2}, "1b": {Add: 2}}, ApprovedBy: "board", ApprovedOn: "today"}
+	if err := scaling.CheckParts(testParts); err != nil {
+		t.Fatal(err)
+	}
+
+	summary := SummariseMarking(form_values, testParts, ValidationOptions{Scaling: scaling, Grades: GradeBoundaries{{"P", 50}, {"F", 0}}})
+
+	script := summary.Scripts[0]
+	// 2 goes up to 4, 1b stays at the 6 available, then 1 is added to the total of 12
+	if script.Total != 10 || script.ScaledTotal == nil || *script.ScaledTotal != 13 || script.ScaledMarks["2"] != 4 || script.Grade != "P" {
+		t.Errorf("got %+v", script)
+	}
+	if summary.Cell(script, "Scaled Total") != "13" || summary.TotalColumns()[1] != "Scaled Total" {
+		t.Errorf("got columns %v", summary.TotalColumns())
+	}
+	if summary.Scripts[1].ScaledTotal != nil {
+		t.Errorf("unmarked script was scaled")
+	}
+	if err := (&Scaling{Parts: map[string]Adjustment{"9z": {}}}).CheckParts(testParts); err == nil {
+		t.Errorf("expected an error for an unknown part")
+	}
+}
+
+func TestAdjustedTotal(t *testing.T) {
+
+	parts := []*PaperStructure{
+		{Part: "1", Marks: 10},
+		{Part: "2", Marks: 10, Choice: "A", Choose: 1},
+		{Part: "3", Marks: 10, Choice: "A", Choose: 1},
+	}
+	form_values := []FormValues{
+		markEntry("B000001", "GK", 1, 0, "4.5"),
+		markEntry("B000001", "GK", 2, 1, "6"),
+		markEntry("B000001", "GK", 3, 2, "5"),
+	}
+	// 1 goes down to 2.5, and 3 goes up to 8 so that it counts in place of 2
+	scaling := &Scaling{Parts: map[string]Adjustment{"1": {Multiply: float(0), Add: 2.5}, "3": {Add: 3}}, ApprovedBy: "board", ApprovedOn: "today"}
+	if err := scaling.check(); err != nil {
+		t.Fatal(err)
+	}
+
+	summary := SummariseMarking(form_values, parts, ValidationOptions{Scaling: scaling, TotalRounding: RoundUp})
+
+	script := summary.Scripts[0]
+	// 4.5 + 6 rounds up to 11, and 2.5 + 8 rounds up to 11 too
+	if script.Total != 11 || script.ScaledTotal == nil || *script.ScaledTotal != 11 || script.ScaledMarks["1"] != 2.5 || script.ScaledMarks["3"] != 8 {
+		t.Errorf("got %+v", script)
+	}
+}
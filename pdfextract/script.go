@@ -0,0 +1,167 @@
+package pdfextract
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	extractor "github.com/timdrysdale/unipdf/v3/extractor"
+	pdf "github.com/timdrysdale/unipdf/v3/model"
+)
+
+// A script PDF that is opened and parsed once, so that the header text and the form fields
+// can both be read from the same parse. Close the script once you are finished with it.
+type Script struct {
+	Path   string
+	file   *os.File
+	reader *pdf.PdfReader
+}
+
+// OpenScript opens and parses the PDF at path, decrypting it with an empty password if needed
+func OpenScript(path string) (*Script, error) {
+	return openScript(path, cmdOptions{})
+}
+
+func openScript(path string, opt cmdOptions) (*Script, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Problem creating reader %s: %v", path, err)
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// Try decrypting with an empty one.
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(opt.pdfPassword))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		if !auth {
+			f.Close()
+			return nil, errors.New("Unable to decrypt password protected file - need to specify pass to Decrypt")
+		}
+	}
+
+	return &Script{Path: path, file: f, reader: pdfReader}, nil
+}
+
+func (script *Script) Close() error {
+	return script.file.Close()
+}
+
+// NumPages is the number of pages in the script
+func (script *Script) NumPages() int {
+	return len(script.reader.PageList)
+}
+
+// PageText extracts the text from page p (counting from 0)
+func (script *Script) PageText(p int) (string, error) {
+	if p < 0 || p >= len(script.reader.PageList) {
+		return "", fmt.Errorf("%s has no page %d", script.Path, p+1)
+	}
+	ex, err := extractor.New(script.reader.PageList[p])
+	if err != nil {
+		return "", err
+	}
+	return ex.ExtractText()
+}
+
+// HeaderText gives the text of the first page, which has the course code, exam number and marker initials in its header.
+// It is keyed by page, like Text, so that it can be passed to the extract* functions.
+func (script *Script) HeaderText() (map[int]string, error) {
+	texts := make(map[int]string)
+	text, err := script.PageText(0)
+	if err != nil {
+		return texts, err
+	}
+	texts[0] = text
+	return texts, nil
+}
+
+// Text gives the text of every page that the text can be extracted from
+func (script *Script) Text() map[int]string {
+	texts := make(map[int]string)
+	for p := range script.reader.PageList {
+		if text, err := script.PageText(p); err == nil {
+			texts[p] = text
+		}
+	}
+	return texts
+}
+
+// Fields gives the value of every form field, keyed by the full field name
+func (script *Script) Fields() map[string]string {
+
+	textfields := make(map[string]string)
+
+	acroForm := script.reader.AcroForm
+	if acroForm == nil {
+		return textfields
+	}
+
+	fields := acroForm.AllFields()
+	for _, field := range fields {
+		fullname, err := field.FullName()
+		if err != nil {
+			continue
+		}
+
+		val := ""
+
+		if field.V != nil {
+			val = field.V.String()
+		}
+
+		textfields[fullname] = val
+
+	}
+
+	return textfields
+}
+
+// PrintFields prints the value of targetFieldName, or of every field if targetFieldName is empty
+func (script *Script) PrintFields(targetFieldName string) error {
+
+	fmt.Printf("Input file: %s\n", script.Path)
+
+	acroForm := script.reader.AcroForm
+	if acroForm == nil {
+		fmt.Printf(" No formdata present\n")
+		return nil
+	}
+
+	match := false
+	fields := acroForm.AllFields()
+	for _, field := range fields {
+		fullname, err := field.FullName()
+		if err != nil {
+			return err
+		}
+		if fullname == targetFieldName || targetFieldName == "" {
+			match = true
+			if field.V != nil {
+				fmt.Printf("Field '%s': '%v' (%T)\n", fullname, field.V, field.V)
+			} else {
+				fmt.Printf("Field '%s': not filled\n", fullname)
+			}
+		}
+	}
+
+	if !match {
+		return errors.New("field not found")
+	}
+	return nil
+}
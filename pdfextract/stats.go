@@ -0,0 +1,150 @@
+package pdfextract
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Descriptive statistics for lists of marks
+
+func mean(marks []float64) float64 {
+	if len(marks) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, mark := range marks {
+		sum = sum + mark
+	}
+	return sum / float64(len(marks))
+}
+
+func median(marks []float64) float64 {
+	if len(marks) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, marks...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// standardDeviation is the sample standard deviation, or 0 if there are fewer than 2 marks
+func standardDeviation(marks []float64) float64 {
+	if len(marks) < 2 {
+		return 0
+	}
+	m := mean(marks)
+	sum_sq := 0.0
+	for _, mark := range marks {
+		sum_sq = sum_sq + (mark-m)*(mark-m)
+	}
+	return math.Sqrt(sum_sq / float64(len(marks)-1))
+}
+
+// quantile interpolates between the closest ranks, so that the 0.5 quantile is the median
+func quantile(marks []float64, q float64) float64 {
+	if len(marks) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, marks...)
+	sort.Float64s(sorted)
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (pos-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// Distribution describes the marks awarded for a part (or the script totals)
+type Distribution struct {
+	Count         int     `json:"count"`
+	Median        float64 `json:"median"`
+	SD            float64 `json:"sd"`
+	Min           float64 `json:"min"`
+	Max           float64 `json:"max"`
+	LowerQuartile float64 `json:"lower_quartile"`
+	UpperQuartile float64 `json:"upper_quartile"`
+	Zero          int     `json:"zero"` // number of scripts with no marks
+	Full          int     `json:"full"` // number of scripts with full marks
+}
+
+// describe gives the distribution of the marks, or nil if there are none
+func describe(marks []float64, out_of float64) *Distribution {
+	if len(marks) == 0 {
+		return nil
+	}
+	d := &Distribution{
+		Count:         len(marks),
+		Median:        median(marks),
+		SD:            standardDeviation(marks),
+		Min:           quantile(marks, 0),
+		Max:           quantile(marks, 1),
+		LowerQuartile: quantile(marks, 0.25),
+		UpperQuartile: quantile(marks, 0.75),
+	}
+	for _, mark := range marks {
+		if mark == 0 {
+			d.Zero++
+		}
+		if out_of > 0 && mark >= out_of {
+			d.Full++
+		}
+	}
+	return d
+}
+
+// A band of the histogram of script totals
+type HistogramBand struct {
+	Label string  `json:"label"` // e.g. "40-50%"
+	From  float64 `json:"from"`  // in marks, including From
+	To    float64 `json:"to"`    // up to but not including To, apart from the last band
+	Count int     `json:"count"`
+}
+
+// histogram counts the totals in bands that are width percent of out_of wide
+func histogram(totals []float64, out_of float64, width float64) []HistogramBand {
+	if width <= 0 || width > 100 {
+		width = 10
+	}
+	bands := []HistogramBand{}
+	for from := 0.0; from < 100-1e-9; from = from + width {
+		to := math.Min(from+width, 100)
+		bands = append(bands, HistogramBand{
+			Label: strconv.FormatFloat(from, 'f', -1, 64) + "-" + strconv.FormatFloat(to, 'f', -1, 64) + "%",
+			From:  out_of * from / 100,
+			To:    out_of * to / 100,
+		})
+	}
+	for _, total := range totals {
+		for i := range bands {
+			if total < bands[i].To || i == len(bands)-1 {
+				bands[i].Count++
+				break
+			}
+		}
+	}
+	return bands
+}
+
+// correlation is Pearson's correlation coefficient, which is not defined if either list of marks has no spread
+func correlation(xs []float64, ys []float64) (float64, bool) {
+	if len(xs) != len(ys) || len(xs) < 2 {
+		return 0, false
+	}
+	mx, my := mean(xs), mean(ys)
+	sxy, sxx, syy := 0.0, 0.0, 0.0
+	for i := range xs {
+		sxy = sxy + (xs[i]-mx)*(ys[i]-my)
+		sxx = sxx + (xs[i]-mx)*(xs[i]-mx)
+		syy = syy + (ys[i]-my)*(ys[i]-my)
+	}
+	if sxx == 0 || syy == 0 {
+		return 0, false
+	}
+	return sxy / math.Sqrt(sxx*syy), true
+}
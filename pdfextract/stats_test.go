@@ -0,0 +1,44 @@
+package pdfextract
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	marks := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	if mean(marks) != 5 || median(marks) != 4.5 || math.Abs(standardDeviation(marks)-2.138) > 0.001 {
+		t.Errorf("got mean %v, median %v, sd %v", mean(marks), median(marks), standardDeviation(marks))
+	}
+	if median([]float64{3, 1, 2}) != 2 || standardDeviation([]float64{3}) != 0 {
+		t.Errorf("odd-length median or single mark sd")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	d := describe([]float64{0, 2, 4, 6, 10, 10}, 10)
+	want := &Distribution{Count: 6, Median: 5, SD: d.SD, Min: 0, Max: 10, LowerQuartile: 2.5, UpperQuartile: 9, Zero: 1, Full: 2}
+	if !reflect.DeepEqual(d, want) || math.Abs(d.SD-4.1312) > 0.0001 {
+		t.Errorf("got %+v", d)
+	}
+	if describe(nil, 10) != nil {
+		t.Errorf("expected no distribution without marks")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	bands := histogram([]float64{0, 9.5, 10, 39, 40}, 40, 25)
+	want := []HistogramBand{
+		{Label: "0-25%", From: 0, To: 10, Count: 2},
+		{Label: "25-50%", From: 10, To: 20, Count: 1},
+		{Label: "50-75%", From: 20, To: 30, Count: 0},
+		{Label: "75-100%", From: 30, To: 40, Count: 2},
+	}
+	if !reflect.DeepEqual(bands, want) {
+		t.Errorf("got %+v", bands)
+	}
+	if bands = histogram(nil, 40, 0); len(bands) != 10 || bands[9].Label != "90-100%" {
+		t.Errorf("default bands: %+v", bands)
+	}
+}
@@ -0,0 +1,125 @@
+package pdfextract
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A group of parts whose marks are added together in the summary, e.g. all the parts of question 1, or of section A
+type PartGroup struct {
+	Name  string   // column heading in the summary, e.g. "Q1" or "Section A"
+	Level string   // "part", "question" or "section"
+	Parts []string // the labels of the parts in this group, in paper order
+	Marks float64  // the marks available for the whole group
+}
+
+// Label is the name used for this part in the summary, e.g. "1a" or "1a(ii)" for a sub-part
+func (part *PaperStructure) Label() string {
+	if part.Subpart == "" {
+		return part.Part
+	}
+	return part.Part + "(" + part.Subpart + ")"
+}
+
+// QuestionName is the question this part belongs to - if not given in the parts csv,
+// it is taken from the leading number of the part, e.g. "12b" is from question "12"
+func (part *PaperStructure) QuestionName() string {
+	if part.Question != "" {
+		return part.Question
+	}
+	leading_number := regexp.MustCompile("^[0-9]+")
+	if qn := leading_number.FindString(part.Part); qn != "" {
+		return qn
+	}
+	return part.Part
+}
+
+// markedParts skips the blank rows of the parts csv, which have no form field
+func markedParts(parts []*PaperStructure) []*PaperStructure {
+	marked := []*PaperStructure{}
+	for _, part := range parts {
+		if part.Part != "" {
+			marked = append(marked, part)
+		}
+	}
+	return marked
+}
+
+// groupParts collects the parts into groups (in the order they first appear), using key to choose each part's group
+func groupParts(parts []*PaperStructure, level string, key func(*PaperStructure) string, heading func(string) string) []PartGroup {
+	groups := []PartGroup{}
+	index := make(map[string]int)
+	for _, part := range markedParts(parts) {
+		name := key(part)
+		if name == "" {
+			continue
+		}
+		if _, ok := index[name]; !ok {
+			index[name] = len(groups)
+			groups = append(groups, PartGroup{Name: heading(name), Level: level})
+		}
+		g := &groups[index[name]]
+		g.Parts = append(g.Parts, part.Label())
+		g.Marks = g.Marks + part.Marks
+	}
+	return groups
+}
+
+// QuestionGroups gives the parts that make up each question
+func QuestionGroups(parts []*PaperStructure) []PartGroup {
+	return groupParts(parts, "question", (*PaperStructure).QuestionName, func(qn string) string {
+		if strings.HasPrefix(strings.ToUpper(qn), "Q") {
+			return qn
+		}
+		return "Q" + qn
+	})
+}
+
+// SubpartGroups gives the sub-parts that make up each part, for parts that have been split into sub-parts
+func SubpartGroups(parts []*PaperStructure) []PartGroup {
+	return groupParts(parts, "part", func(part *PaperStructure) string {
+		if part.Subpart == "" {
+			return ""
+		}
+		return part.Part
+	}, func(name string) string { return name })
+}
+
+// SectionGroups gives the parts that make up each section of the paper (if sections are used)
+// A section's marks only count the questions that can be chosen from any choice groups in it
+func SectionGroups(parts []*PaperStructure) []PartGroup {
+	sections := groupParts(parts, "section", func(part *PaperStructure) string { return part.Section }, func(name string) string {
+		if strings.HasPrefix(strings.ToLower(name), "section") {
+			return name
+		}
+		return "Section " + name
+	})
+	for i := range sections {
+		in_section := []*PaperStructure{}
+		for _, part := range markedParts(parts) {
+			for _, pname := range sections[i].Parts {
+				if part.Label() == pname {
+					in_section = append(in_section, part)
+				}
+			}
+		}
+		sections[i].Marks = OutOf(in_section)
+	}
+	return sections
+}
+
+// SubtotalGroups lists the subtotal columns for the summary: parts split into sub-parts, then questions
+// made up of more than one part, then sections
+func SubtotalGroups(parts []*PaperStructure) []PartGroup {
+	subtotals := SubpartGroups(parts)
+	already_totalled := make(map[string]bool)
+	for _, g := range subtotals {
+		already_totalled[strings.Join(g.Parts, ",")] = true
+	}
+	for _, qn := range QuestionGroups(parts) {
+		if len(qn.Parts) > 1 && !already_totalled[strings.Join(qn.Parts, ",")] {
+			subtotals = append(subtotals, qn)
+		}
+	}
+	return append(subtotals, SectionGroups(parts)...)
+}
@@ -0,0 +1,29 @@
+package pdfextract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubtotalGroups(t *testing.T) {
+
+	parts := []*PaperStructure{
+		{Section: "A", Part: "1a", Marks: 2},
+		{Section: "A", Part: "1b", Subpart: "i", Marks: 3},
+		{Section: "A", Part: "1b", Subpart: "ii", Marks: 1},
+		{},
+		{Section: "B", Part: "2", Marks: 10},
+		{Section: "B", Question: "3", Part: "extra", Marks: 4},
+	}
+
+	want := []PartGroup{
+		{Name: "1b", Level: "part", Parts: []string{"1b(i)", "1b(ii)"}, Marks: 4},
+		{Name: "Q1", Level: "question", Parts: []string{"1a", "1b(i)", "1b(ii)"}, Marks: 6},
+		{Name: "Section A", Level: "section", Parts: []string{"1a", "1b(i)", "1b(ii)"}, Marks: 6},
+		{Name: "Section B", Level: "section", Parts: []string{"2", "extra"}, Marks: 14},
+	}
+
+	if got := SubtotalGroups(parts); !reflect.DeepEqual(got, want) {
+		t.Errorf("SubtotalGroups() = %+v; want %+v", got, want)
+	}
+}
@@ -0,0 +1,428 @@
+package pdfextract
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The status of each script in the summary
+const (
+	StatusInvalid  = "invalid"  // there are validation problems or unmarked pages
+	StatusComplete = "complete" // marking completed
+	StatusUnmarked = "unmarked" // yet to be marked
+)
+
+// MarksSummary is the outcome of SummariseMarking, which the csv and json summaries are written from
+type MarksSummary struct {
+	Course     string            `json:"course"`
+	Markers    []string          `json:"markers"`
+	Parts      []ColumnSummary   `json:"parts"`
+	Subtotals  []ColumnSummary   `json:"subtotals,omitempty"`
+	OutOf      float64           `json:"out_of"`
+	Statistics SummaryStatistics `json:"statistics"`
+	Grades     []GradeCount      `json:"grades,omitempty"` // how many marked scripts got each grade, if there are grade boundaries
+	Scaling    *Scaling          `json:"scaling,omitempty"`
+	Scripts    []ScriptSummary   `json:"scripts"` // sorted by exam number
+}
+
+// A column of marks in the summary: either a part of the paper, or a subtotal of several parts
+type ColumnSummary struct {
+	Name         string        `json:"name"`
+	Level        string        `json:"level,omitempty"` // for subtotals: "part", "question" or "section"
+	Parts        []string      `json:"parts,omitempty"` // for subtotals: the parts added up
+	OutOf        float64       `json:"out_of"`
+	Granularity  float64       `json:"granularity,omitempty"`
+	Optional     bool          `json:"optional,omitempty"` // averaged over the scripts that answered it, rather than all scripts
+	Mean         *float64      `json:"mean"`               // nil until some marks have been awarded
+	MeanPercent  *float64      `json:"mean_percent"`
+	Distribution *Distribution `json:"distribution"` // nil until some marks have been awarded
+}
+
+type SummaryStatistics struct {
+	Scripts      int             `json:"scripts"`
+	Invalid      int             `json:"invalid"`
+	Complete     int             `json:"complete"`
+	Unmarked     int             `json:"unmarked"`
+	Mean         *float64        `json:"mean"` // mean total of the marked scripts
+	MeanPercent  *float64        `json:"mean_percent"`
+	Distribution *Distribution   `json:"distribution"` // of the totals of the marked scripts
+	Histogram    []HistogramBand `json:"histogram,omitempty"`
+}
+
+// The marks for one script
+type ScriptSummary struct {
+	ExamNumber    string              `json:"exam_number"`
+	Status        string              `json:"status"`
+	Markers       []string            `json:"markers,omitempty"`       // the initials on this script's pages
+	MarksEntered  map[string][]string `json:"marks_entered,omitempty"` // the values typed for each part, e.g. ["4", "5"] if marked twice
+	Marks         map[string]float64  `json:"marks,omitempty"`         // the mark for each part and subtotal
+	Total         float64             `json:"total"`
+	ScaledMarks   map[string]float64  `json:"scaled_marks,omitempty"` // the parts adjusted by the scaling
+	ScaledTotal   *float64            `json:"scaled_total,omitempty"`
+	Percentage    *float64            `json:"percentage,omitempty"` // only worked out when there are grade boundaries
+	Grade         string              `json:"grade,omitempty"`
+	Validation    map[string]string   `json:"validation,omitempty"` // e.g. validation["1a"] = "not marked"
+	Warnings      map[string]string   `json:"warnings,omitempty"`   // notes that don't count against the marking, e.g. warnings["choice B"] = "answered 1 of 2 questions (Q3)"
+	UnmarkedPages []int               `json:"unmarked_pages,omitempty"`
+	BadPages      []int               `json:"bad_pages,omitempty"`
+	DroppedParts  []string            `json:"dropped_parts,omitempty"` // parts of optional questions left out of the total, as better ones were answered
+}
+
+// Columns gives the parts followed by the subtotals, in the order they appear in the summary
+func (summary *MarksSummary) Columns() []ColumnSummary {
+	return append(append([]ColumnSummary{}, summary.Parts...), summary.Subtotals...)
+}
+
+// TotalColumns are the columns that follow the marks: the Total, the Scaled Total if the marks have been scaled,
+// and the Percentage and Grade if there are grade boundaries
+func (summary *MarksSummary) TotalColumns() []string {
+	columns := []string{"Total"}
+	if summary.Scaling != nil {
+		columns = append(columns, "Scaled Total")
+	}
+	if summary.Grades != nil {
+		columns = append(columns, "Percentage", "Grade")
+	}
+	return columns
+}
+
+// ScriptsWithStatus picks out the scripts with the given status, in exam number order
+func (summary *MarksSummary) ScriptsWithStatus(status string) []ScriptSummary {
+	scripts := []ScriptSummary{}
+	for _, script := range summary.Scripts {
+		if script.Status == status {
+			scripts = append(scripts, script)
+		}
+	}
+	return scripts
+}
+
+// ValidationString lists the validation problems as they appear in the summary, e.g. "1a: not marked; 2: max mark is 5",
+// followed by any warnings
+func (script ScriptSummary) ValidationString() string {
+	problems := make([]string, 0, len(script.Validation))
+	for pname, problem := range script.Validation {
+		problems = append(problems, pname+": "+problem)
+	}
+	sort.Strings(problems)
+	warnings := make([]string, 0, len(script.Warnings))
+	for name, warning := range script.Warnings {
+		warnings = append(warnings, name+": "+warning)
+	}
+	sort.Strings(warnings)
+	return strings.Join(append(problems, warnings...), "; ")
+}
+
+// Cell gives the entry in the summary table for a column of marks, one of the TotalColumns, or one of the Validation,
+// Unmarked Pages and Bad Pages columns
+func (summary *MarksSummary) Cell(script ScriptSummary, column string) string {
+	switch column {
+	case "Total":
+		return formatMark(script.Total)
+	case "Scaled Total":
+		if script.ScaledTotal == nil {
+			return ""
+		}
+		return formatMark(*script.ScaledTotal)
+	case "Percentage":
+		return meanString(script.Percentage, "%.1f")
+	case "Grade":
+		return script.Grade
+	case "Validation":
+		return script.ValidationString()
+	case "Unmarked Pages":
+		return pageList(script.UnmarkedPages)
+	case "Bad Pages":
+		return pageList(script.BadPages)
+	}
+	for _, part := range summary.Parts {
+		if part.Name == column {
+			return strings.Join(script.MarksEntered[column], " + ")
+		}
+	}
+	if mark, ok := script.Marks[column]; ok {
+		return formatMark(mark)
+	}
+	return ""
+}
+
+func pageList(pages []int) string {
+	return strings.Trim(strings.Join(strings.Fields(fmt.Sprint(pages)), ", "), "[]") // https://stackoverflow.com/a/37533144
+}
+
+// The statistics from a Distribution that are shown in the summary, after the means
+var distributionStats = []struct {
+	label string
+	value func(d *Distribution) string
+}{
+	{"median:", func(d *Distribution) string { return formatMark(d.Median) }},
+	{"sd:", func(d *Distribution) string { return strconv.FormatFloat(d.SD, 'f', 2, 64) }},
+	{"min:", func(d *Distribution) string { return formatMark(d.Min) }},
+	{"lower quartile:", func(d *Distribution) string { return formatMark(d.LowerQuartile) }},
+	{"upper quartile:", func(d *Distribution) string { return formatMark(d.UpperQuartile) }},
+	{"max:", func(d *Distribution) string { return formatMark(d.Max) }},
+	{"zero marks:", func(d *Distribution) string { return strconv.Itoa(d.Zero) }},
+	{"full marks:", func(d *Distribution) string { return strconv.Itoa(d.Full) }},
+}
+
+func distributionString(d *Distribution, value func(d *Distribution) string) string {
+	if d == nil {
+		return ""
+	}
+	return value(d)
+}
+
+func meanString(mean *float64, format string) string {
+	if mean == nil {
+		return ""
+	}
+	return fmt.Sprintf(format, *mean)
+}
+
+// WriteSummaryCSV writes the summary for people to read: some details of the marking, the means for each part,
+// then separate blocks for the scripts with validation problems, the completed scripts and those yet to be marked
+func WriteSummaryCSV(summary *MarksSummary, outputCSV string) error {
+
+	fmt.Printf("\n\nWriting summary for %d scripts\n", len(summary.Scripts))
+
+	file, err := os.OpenFile(outputCSV, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+
+	// Basic info about the marking
+	w.Write([]string{"Exam: ", summary.Course})
+	w.Write([]string{"Marker: ", sliceToCommaString(summary.Markers)})
+	w.Write([]string{""})
+
+	// Prepare the headers
+	columns := summary.Columns()
+	mark_columns := make([]string, 0, len(columns))
+	for _, column := range columns {
+		mark_columns = append(mark_columns, column.Name)
+	}
+	csv_headers := append([]string{"Exam Number"}, mark_columns...)
+	csv_headers = append(csv_headers, summary.TotalColumns()...)
+	csv_headers = append(csv_headers, []string{"Validation", "Unmarked Pages", "Bad Pages"}...)
+
+	// Write the header and stats summary rows
+	w.Write(append([]string{""}, append(mark_columns, "Total")...))
+
+	// Add a row showing what each question is marked out of, and rows showing the item means
+	row_outof := []string{"out of:"}
+	row_means := []string{"mean:"}
+	row_means_pc := []string{"mean (%):"}
+	for _, column := range columns {
+		row_outof = append(row_outof, formatMark(column.OutOf))
+		row_means = append(row_means, meanString(column.Mean, "%.2f"))
+		row_means_pc = append(row_means_pc, meanString(column.MeanPercent, "%.1f"))
+	}
+	row_outof = append(row_outof, formatMark(summary.OutOf))
+	if summary.Statistics.Mean != nil {
+		row_means = append(row_means, meanString(summary.Statistics.Mean, "%.2f"))
+		row_means_pc = append(row_means_pc, meanString(summary.Statistics.MeanPercent, "%.1f"))
+	}
+	w.Write(row_outof)
+	w.Write(row_means)
+	w.Write(row_means_pc)
+
+	// and the rest of the statistics for each column
+	for _, stat := range distributionStats {
+		row := []string{stat.label}
+		for _, column := range columns {
+			row = append(row, distributionString(column.Distribution, stat.value))
+		}
+		row = append(row, distributionString(summary.Statistics.Distribution, stat.value))
+		w.Write(row)
+	}
+
+	// Print each row for the invalid records, then the valid ones - range over the csv_headers to look up the correct value for each column
+	blocks := []struct {
+		status  string
+		heading string
+	}{
+		{StatusInvalid, "Validation problems"},
+		{StatusComplete, "Marking completed"},
+	}
+	for _, block := range blocks {
+		scripts := summary.ScriptsWithStatus(block.status)
+		w.Write([]string{""}) // blank row
+		w.Write([]string{block.heading + " (" + strconv.Itoa(len(scripts)) + " scripts):"})
+		w.Write(csv_headers)
+		for _, script := range scripts {
+			record := []string{script.ExamNumber}
+			for _, val := range csv_headers {
+				if val == "Exam Number" {
+					continue
+				}
+				record = append(record, summary.Cell(script, val))
+			}
+			w.Write(record)
+		}
+	}
+
+	// Now do the unmarked ones
+	unmarked := summary.ScriptsWithStatus(StatusUnmarked)
+	w.Write([]string{""}) // blank row
+	w.Write([]string{"Yet to be marked (" + strconv.Itoa(len(unmarked)) + " scripts):"})
+	for _, script := range unmarked {
+		w.Write([]string{script.ExamNumber})
+	}
+
+	// The grades, if there are grade boundaries
+	if summary.Grades != nil {
+		w.Write([]string{""}) // blank row
+		w.Write([]string{"Grade distribution:"})
+		w.Write([]string{"Grade", "From (%)", "Scripts"})
+		for _, grade := range summary.Grades {
+			w.Write([]string{grade.Grade, formatMark(grade.From), strconv.Itoa(grade.Count)})
+		}
+	}
+
+	// Finally, how the totals are spread out
+	if len(summary.Statistics.Histogram) > 0 {
+		w.Write([]string{""}) // blank row
+		w.Write([]string{"Distribution of totals:"})
+		w.Write([]string{"Band", "From", "To", "Scripts"})
+		for _, band := range summary.Statistics.Histogram {
+			w.Write([]string{band.Label, formatMark(band.From), formatMark(band.To), strconv.Itoa(band.Count)})
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// WriteSummaryJSON writes the summary in a structured form for other programs to read
+func WriteSummaryJSON(summary *MarksSummary, outputJSON string) error {
+	json_data, err := json.MarshalIndent(summary, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(outputJSON, json_data, os.ModePerm)
+}
+
+// WriteSummaryXLSX writes the summary as a workbook, with a sheet for each block of scripts, the statistics for
+// each part, the distribution of totals, and the raw form values. Cells with validation problems are highlighted.
+func WriteSummaryXLSX(summary *MarksSummary, form_values []FormValues, outputXLSX string) error {
+
+	columns := summary.Columns()
+	headings := []string{"Exam Number"}
+	for _, column := range columns {
+		headings = append(headings, column.Name)
+	}
+	headings = append(headings, summary.TotalColumns()...)
+	headings = append(headings, "Validation", "Unmarked Pages", "Bad Pages")
+
+	scriptSheet := func(name string, status string) xlsxSheet {
+		sheet := xlsxSheet{Name: name, Rows: [][]xlsxCell{headerRow(headings)}, FrozenRows: 1, FrozenCols: 1}
+		for _, script := range summary.ScriptsWithStatus(status) {
+			row := []xlsxCell{textCell(script.ExamNumber, styleNormal)}
+			for _, heading := range headings[1:] {
+				style := styleNormal
+				if _, problem := script.Validation[heading]; problem {
+					style = styleHighlight
+				}
+				if heading == "Validation" || heading == "Unmarked Pages" {
+					if summary.Cell(script, heading) != "" {
+						style = styleHighlight
+					}
+				}
+				row = append(row, markCell(summary.Cell(script, heading), style))
+			}
+			sheet.Rows = append(sheet.Rows, row)
+		}
+		return sheet
+	}
+
+	unmarked := xlsxSheet{Name: "Yet to be marked", Rows: [][]xlsxCell{headerRow([]string{"Exam Number"})}, FrozenRows: 1}
+	for _, script := range summary.ScriptsWithStatus(StatusUnmarked) {
+		unmarked.Rows = append(unmarked.Rows, []xlsxCell{textCell(script.ExamNumber, styleNormal)})
+	}
+
+	statistics_headings := []string{"Part", "Level", "Parts", "Out of", "Mean", "Mean (%)"}
+	for _, stat := range distributionStats {
+		statistics_headings = append(statistics_headings, strings.TrimSuffix(stat.label, ":"))
+	}
+	statistics := xlsxSheet{Name: "Part statistics", FrozenRows: 1, FrozenCols: 1, Rows: [][]xlsxCell{headerRow(statistics_headings)}}
+	for _, column := range columns {
+		row := []xlsxCell{
+			textCell(column.Name, styleNormal),
+			textCell(column.Level, styleNormal),
+			textCell(sliceToCommaString(column.Parts), styleNormal),
+			markCell(formatMark(column.OutOf), styleNormal),
+			markCell(meanString(column.Mean, "%.2f"), styleNormal),
+			markCell(meanString(column.MeanPercent, "%.1f"), styleNormal),
+		}
+		for _, stat := range distributionStats {
+			row = append(row, markCell(distributionString(column.Distribution, stat.value), styleNormal))
+		}
+		statistics.Rows = append(statistics.Rows, row)
+	}
+	total_row := []xlsxCell{
+		textCell("Total", styleHeader),
+		textCell("", styleNormal),
+		textCell("", styleNormal),
+		markCell(formatMark(summary.OutOf), styleNormal),
+		markCell(meanString(summary.Statistics.Mean, "%.2f"), styleNormal),
+		markCell(meanString(summary.Statistics.MeanPercent, "%.1f"), styleNormal),
+	}
+	for _, stat := range distributionStats {
+		total_row = append(total_row, markCell(distributionString(summary.Statistics.Distribution, stat.value), styleNormal))
+	}
+	statistics.Rows = append(statistics.Rows, total_row)
+
+	totals := xlsxSheet{Name: "Distribution of totals", FrozenRows: 1, Rows: [][]xlsxCell{headerRow([]string{"Band", "From", "To", "Scripts"})}}
+	for _, band := range summary.Statistics.Histogram {
+		totals.Rows = append(totals.Rows, []xlsxCell{
+			textCell(band.Label, styleNormal),
+			markCell(formatMark(band.From), styleNormal),
+			markCell(formatMark(band.To), styleNormal),
+			markCell(strconv.Itoa(band.Count), styleNormal),
+		})
+	}
+
+	raw := xlsxSheet{Name: "Raw form values", FrozenRows: 1,
+		Rows: [][]xlsxCell{headerRow([]string{"CourseCode", "Marker", "ExamNumber", "Page", "Field", "FieldName", "Value"})}}
+	for _, entry := range form_values {
+		raw.Rows = append(raw.Rows, []xlsxCell{
+			textCell(entry.CourseCode, styleNormal),
+			textCell(entry.Marker, styleNormal),
+			textCell(entry.ExamNumber, styleNormal),
+			markCell(strconv.Itoa(entry.Page), styleNormal),
+			textCell(entry.Field, styleNormal),
+			textCell(entry.FieldName, styleNormal),
+			textCell(entry.Value, styleNormal),
+		})
+	}
+
+	sheets := []xlsxSheet{
+		scriptSheet("Validation problems", StatusInvalid),
+		scriptSheet("Marking completed", StatusComplete),
+		unmarked,
+		statistics,
+		totals,
+	}
+	if summary.Grades != nil {
+		grades := xlsxSheet{Name: "Grade distribution", FrozenRows: 1, Rows: [][]xlsxCell{headerRow([]string{"Grade", "From (%)", "Scripts"})}}
+		for _, grade := range summary.Grades {
+			grades.Rows = append(grades.Rows, []xlsxCell{
+				textCell(grade.Grade, styleNormal),
+				markCell(formatMark(grade.From), styleNormal),
+				markCell(strconv.Itoa(grade.Count), styleNormal),
+			})
+		}
+		sheets = append(sheets, grades)
+	}
+	sheets = append(sheets, raw)
+
+	return writeXLSX(outputXLSX, sheets)
+}
@@ -0,0 +1,135 @@
+package pdfextract
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// markEntry is the form value for a mark typed into the box for part pnum on page (counting from 1)
+func markEntry(examno string, marker string, page int, pnum int, value string) FormValues {
+	field := fmt.Sprintf("page-%03d-qn-part-mark-%d", page-1, pnum)
+	return FormValues{CourseCode: "MATH01234", Marker: marker, ExamNumber: examno, Page: page, Field: field, FieldName: "qn-part-mark-" + fmt.Sprint(pnum), Value: value}
+}
+
+func seenEntry(examno string, marker string, page int) FormValues {
+	field := fmt.Sprintf("page-%03d-page-seen", page-1)
+	return FormValues{CourseCode: "MATH01234", Marker: marker, ExamNumber: examno, Page: page, Field: field, FieldName: "page-seen", Value: "Yes"}
+}
+
+func blankEntry(examno string, page int) FormValues {
+	field := fmt.Sprintf("page-%03d-page-seen", page-1)
+	return FormValues{CourseCode: "MATH01234", ExamNumber: examno, Page: page, Field: field, FieldName: "page-seen"}
+}
+
+var testParts = []*PaperStructure{
+	{Part: "1a", Marks: 4, Granularity: 0.5},
+	{Part: "1b", Marks: 6, Granularity: 1},
+	{Part: "2", Marks: 10, Granularity: 1},
+}
+
+func TestSummariseMarking(t *testing.T) {
+
+	form_values := []FormValues{
+		markEntry("B000001", "GK", 1, 0, "3.5"),
+		markEntry("B000001", "GK", 1, 1, "6"),
+		markEntry("B000001", "GK", 2, 2, "7"),
+		markEntry("B000002", "JS", 1, 0, "4"),
+		markEntry("B000002", "JS", 1, 1, "2.5"),
+		blankEntry("B000002", 2),
+		blankEntry("B000003", 1),
+		blankEntry("B000003", 2),
+	}
+
+	summary := SummariseMarking(form_values, testParts, ValidationOptions{})
+
+	if summary.Course != "MATH01234" || summary.OutOf != 20 || !reflect.DeepEqual(summary.Markers, []string{"GK", "JS"}) {
+		t.Errorf("got course %q, out of %v, markers %v", summary.Course, summary.OutOf, summary.Markers)
+	}
+	if len(summary.Scripts) != 3 {
+		t.Fatalf("got %d scripts", len(summary.Scripts))
+	}
+
+	complete, invalid, unmarked := summary.Scripts[0], summary.Scripts[1], summary.Scripts[2]
+	// each script only lists its own markers
+	if !reflect.DeepEqual(complete.Markers, []string{"GK"}) || !reflect.DeepEqual(invalid.Markers, []string{"JS"}) || unmarked.Markers != nil {
+		t.Errorf("got markers %v, %v and %v", complete.Markers, invalid.Markers, unmarked.Markers)
+	}
+	if complete.Status != StatusComplete || complete.Total != 16.5 || complete.Marks["1a"] != 3.5 {
+		t.Errorf("B000001: %+v", complete)
+	}
+	want_validation := map[string]string{"1b": "marks must be in steps of 1", "2": "not marked"}
+	if invalid.Status != StatusInvalid || !reflect.DeepEqual(invalid.Validation, want_validation) ||
+		!reflect.DeepEqual(invalid.UnmarkedPages, []int{2}) {
+		t.Errorf("B000002: %+v", invalid)
+	}
+	if got := invalid.ValidationString(); got != "1b: marks must be in steps of 1; 2: not marked" {
+		t.Errorf("ValidationString() = %q", got)
+	}
+	if unmarked.Status != StatusUnmarked {
+		t.Errorf("B000003: %+v", unmarked)
+	}
+
+	// means are over the marked scripts
+	if mean := summary.Parts[0].Mean; mean == nil || *mean != 3.75 {
+		t.Errorf("mean for 1a = %v", mean)
+	}
+	if mean := summary.Statistics.Mean; mean == nil || *mean != (16.5+6.5)/2 {
+		t.Errorf("mean total = %v", mean)
+	}
+}
+
+func TestInvalidMarksNotCounted(t *testing.T) {
+
+	form_values := []FormValues{
+		markEntry("B000001", "GK", 1, 0, "4"),
+		markEntry("B000001", "GK", 1, 1, "60"),
+		markEntry("B000001", "GK", 1, 2, "-2"),
+		markEntry("B000002", "GK", 1, 0, "2"),
+		markEntry("B000002", "GK", 1, 1, "6"),
+		markEntry("B000002", "GK", 1, 2, "8"),
+	}
+
+	summary := SummariseMarking(form_values, testParts, ValidationOptions{})
+
+	script := summary.Scripts[0]
+	want_validation := map[string]string{"1b": "max mark is 6", "2": "negative mark"}
+	if script.Total != 4 || script.Marks["1b"] != 0 || !reflect.DeepEqual(script.Validation, want_validation) {
+		t.Errorf("B000001: %+v", script)
+	}
+	if !reflect.DeepEqual(script.MarksEntered["1b"], []string{"60"}) {
+		t.Errorf("marks entered for 1b = %v", script.MarksEntered["1b"])
+	}
+	if mean := summary.Parts[1].Mean; mean == nil || *mean != 3 {
+		t.Errorf("mean for 1b = %v", mean)
+	}
+	if mean := summary.Statistics.Mean; mean == nil || *mean != (4+16)/2 {
+		t.Errorf("mean total = %v", mean)
+	}
+}
+
+func TestSummaryStatistics(t *testing.T) {
+
+	form_values := []FormValues{
+		markEntry("B000001", "GK", 1, 0, "4"),
+		markEntry("B000001", "GK", 1, 1, "6"),
+		markEntry("B000001", "GK", 2, 2, "10"),
+		markEntry("B000002", "GK", 1, 0, "0"),
+		markEntry("B000002", "GK", 1, 1, "2"),
+		markEntry("B000002", "GK", 2, 2, "3"),
+	}
+
+	summary := SummariseMarking(form_values, testParts, ValidationOptions{HistogramWidth: 50})
+
+	part := summary.Parts[0]
+	if part.Name != "1a" || part.Distribution == nil || part.Distribution.Zero != 1 || part.Distribution.Full != 1 || part.Distribution.Median != 2 {
+		t.Errorf("got 1a statistics %+v", part.Distribution)
+	}
+	totals := summary.Statistics.Distribution
+	if totals == nil || totals.Min != 5 || totals.Max != 20 || totals.Count != 2 {
+		t.Errorf("got statistics of totals %+v", totals)
+	}
+	if bands := summary.Statistics.Histogram; len(bands) != 2 || bands[0].Count != 1 || bands[1].Count != 1 {
+		t.Errorf("got histogram %+v", bands)
+	}
+}
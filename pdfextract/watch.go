@@ -0,0 +1,94 @@
+package pdfextract
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// The size and modification time of a PDF, which change while it is being written
+type FileState struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// FolderState is the state of every PDF in a folder (and its sub-folders), keyed by path
+type FolderState map[string]FileState
+
+// ScanPDFs finds the state of every PDF in dir and its sub-folders
+func ScanPDFs(dir string) FolderState {
+	state := make(FolderState)
+	filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+		if err != nil || f.IsDir() || filepath.Ext(f.Name()) != ".pdf" {
+			return nil
+		}
+		state[path] = FileState{Size: f.Size(), ModTime: f.ModTime()}
+		return nil
+	})
+	return state
+}
+
+// ScriptPaths finds the PDFs for each exam number in dir and its sub-folders - there can be more than one
+// when each marker has their own copy of the script, and these are listed in path order
+func ScriptPaths(dir string, conv *Conventions) map[string][]string {
+	if conv == nil {
+		conv = DefaultConventions()
+	}
+	paths := make(map[string][]string)
+	for path := range ScanPDFs(dir) {
+		if examno, ok := conv.ExamNumberFromFilename(filepath.Base(path)); ok {
+			paths[examno] = append(paths[examno], path)
+		}
+	}
+	for _, copies := range paths {
+		sort.Strings(copies)
+	}
+	return paths
+}
+
+func (state FolderState) equal(other FolderState) bool {
+	if len(state) != len(other) {
+		return false
+	}
+	for path, file := range state {
+		if o, ok := other[path]; !ok || o.Size != file.Size || !o.ModTime.Equal(file.ModTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// Watcher decides when a folder has changed and then settled down, so that PDFs that are still being
+// copied in are not read half-written
+type Watcher struct {
+	Settle        time.Duration // how long the folder must stay the same before it is read
+	last          FolderState   // the state when the folder was last read
+	pending       FolderState   // the most recent change that has not been read yet
+	pending_since time.Time
+}
+
+// Ready is given the state of the folder at time now, and says whether it should be read. The first call is always
+// ready, so that the folder is read when watching starts.
+func (w *Watcher) Ready(state FolderState, now time.Time) bool {
+	if w.last != nil && state.equal(w.last) {
+		w.pending = nil
+		return false
+	}
+	if w.last == nil && w.pending == nil {
+		w.last = state
+		return true
+	}
+	if w.pending == nil || !state.equal(w.pending) {
+		// something is still changing, so wait for it to settle
+		w.pending = state
+		w.pending_since = now
+		return false
+	}
+	if now.Sub(w.pending_since) < w.Settle {
+		return false
+	}
+	w.last = state
+	w.pending = nil
+	return true
+}
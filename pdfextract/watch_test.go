@@ -0,0 +1,34 @@
+package pdfextract
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+
+	start := time.Date(2021, 5, 1, 9, 0, 0, 0, time.UTC)
+	one := FolderState{"a.pdf": {Size: 10, ModTime: start}}
+	growing := FolderState{"a.pdf": {Size: 10, ModTime: start}, "b.pdf": {Size: 5, ModTime: start}}
+	grown := FolderState{"a.pdf": {Size: 10, ModTime: start}, "b.pdf": {Size: 50, ModTime: start.Add(time.Second)}}
+
+	w := &Watcher{Settle: 10 * time.Second}
+	steps := []struct {
+		state FolderState
+		after time.Duration
+		ready bool
+	}{
+		{one, 0, true},                     // read when watching starts
+		{one, 5 * time.Second, false},      // nothing has changed
+		{growing, 10 * time.Second, false}, // b.pdf is being copied in
+		{grown, 15 * time.Second, false},   // ... and is still changing
+		{grown, 20 * time.Second, false},   // not settled for long enough yet
+		{grown, 25 * time.Second, true},    // settled
+		{grown, 30 * time.Second, false},   // already read
+	}
+	for i, step := range steps {
+		if ready := w.Ready(step.state, start.Add(step.after)); ready != step.ready {
+			t.Errorf("step %d: Ready() = %v", i, ready)
+		}
+	}
+}
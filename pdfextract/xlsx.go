@@ -0,0 +1,176 @@
+package pdfextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Just enough of the xlsx format to write a workbook of plain sheets, without pulling in a spreadsheet library
+
+// Cell styles, matching the order of cellXfs in xlsxStyles
+const (
+	styleNormal    = 0
+	styleHeader    = 1
+	styleHighlight = 2
+)
+
+type xlsxCell struct {
+	Value  string
+	Number bool // write the value as a number rather than text
+	Style  int
+}
+
+type xlsxSheet struct {
+	Name       string
+	Rows       [][]xlsxCell
+	FrozenRows int
+	FrozenCols int
+}
+
+func textCell(value string, style int) xlsxCell {
+	return xlsxCell{Value: value, Style: style}
+}
+
+// markCell writes a mark as a number where possible, so that it can be used in formulas. Only finite decimals count
+// as numbers - anything else, such as "NaN", "Inf" or a hex float, is written as the text that was typed.
+func markCell(value string, style int) xlsxCell {
+	if mark, err := parseMark(value); err == nil && !strings.ContainsAny(value, "xX") {
+		return xlsxCell{Value: strconv.FormatFloat(mark, 'f', -1, 64), Number: true, Style: style}
+	}
+	return xlsxCell{Value: value, Style: style}
+}
+
+func headerRow(headings []string) []xlsxCell {
+	row := make([]xlsxCell, 0, len(headings))
+	for _, heading := range headings {
+		row = append(row, textCell(heading, styleHeader))
+	}
+	return row
+}
+
+// columnName turns a column number (from 0) into its spreadsheet letters, e.g. 0 -> A, 27 -> AB
+func columnName(col int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name
+}
+
+func xmlEscape(str string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(str))
+	return buf.String()
+}
+
+func (sheet xlsxSheet) xml() string {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if sheet.FrozenRows > 0 || sheet.FrozenCols > 0 {
+		pane := "bottomRight"
+		if sheet.FrozenCols == 0 {
+			pane = "bottomLeft"
+		} else if sheet.FrozenRows == 0 {
+			pane = "topRight"
+		}
+		fmt.Fprintf(&buf, `<sheetViews><sheetView workbookViewId="0"><pane xSplit="%d" ySplit="%d" topLeftCell="%s%d" activePane="%s" state="frozen"/></sheetView></sheetViews>`,
+			sheet.FrozenCols, sheet.FrozenRows, columnName(sheet.FrozenCols), sheet.FrozenRows+1, pane)
+	}
+	buf.WriteString(`<sheetData>`)
+	for r, row := range sheet.Rows {
+		fmt.Fprintf(&buf, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := fmt.Sprintf("%s%d", columnName(c), r+1)
+			switch {
+			case cell.Value == "":
+				fmt.Fprintf(&buf, `<c r="%s" s="%d"/>`, ref, cell.Style)
+			case cell.Number:
+				fmt.Fprintf(&buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.Style, cell.Value)
+			default:
+				fmt.Fprintf(&buf, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, cell.Style, xmlEscape(cell.Value))
+			}
+		}
+		buf.WriteString(`</row>`)
+	}
+	buf.WriteString(`</sheetData></worksheet>`)
+	return buf.String()
+}
+
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFFFC7CE"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="0" fontId="0" fillId="2" borderId="0" xfId="0" applyFill="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// writeXLSX saves the sheets as a workbook
+func writeXLSX(path string, sheets []xlsxSheet) error {
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var content_types, workbook, workbook_rels bytes.Buffer
+	content_types.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	workbook.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	workbook_rels.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i, sheet := range sheets {
+		fmt.Fprintf(&content_types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.Name), i+1, i+1)
+		fmt.Fprintf(&workbook_rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&workbook_rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+	content_types.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	workbook_rels.WriteString(`</Relationships>`)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", content_types.String()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", workbook_rels.String()},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for i, sheet := range sheets {
+		parts = append(parts, struct {
+			name    string
+			content string
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.xml()})
+	}
+
+	z := zip.NewWriter(file)
+	for _, part := range parts {
+		w, err := z.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
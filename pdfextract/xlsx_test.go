@@ -0,0 +1,86 @@
+package pdfextract
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestColumnName(t *testing.T) {
+	for col, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(col); got != want {
+			t.Errorf("columnName(%d) = %s; want %s", col, got, want)
+		}
+	}
+}
+
+func TestMarkCell(t *testing.T) {
+	for value, want := range map[string]xlsxCell{
+		"2.5":      {Value: "2.5", Number: true},
+		"2,5":      {Value: "2.5", Number: true},
+		" 3 ":      {Value: "3", Number: true},
+		"1e1":      {Value: "10", Number: true},
+		"NaN":      {Value: "NaN"},
+		"Inf":      {Value: "Inf"},
+		"-inf":     {Value: "-inf"},
+		"infinity": {Value: "infinity"},
+		"0x1p-2":   {Value: "0x1p-2"},
+		"0X10P0":   {Value: "0X10P0"},
+		"four":     {Value: "four"},
+		"":         {Value: ""},
+	} {
+		if got := markCell(value, styleNormal); got != want {
+			t.Errorf("markCell(%q) = %+v, want %+v", value, got, want)
+		}
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gradex-xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "summary.xlsx")
+
+	sheet := xlsxSheet{Name: "Marks & checks", FrozenRows: 1, Rows: [][]xlsxCell{
+		headerRow([]string{"Exam Number", "1a"}),
+		{textCell("B000001", styleNormal), markCell("2.5", styleHighlight)},
+	}}
+	if err := writeXLSX(path, []xlsxSheet{sheet}); err != nil {
+		t.Fatal(err)
+	}
+
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	contents := make(map[string]string)
+	for _, f := range z.File {
+		r, _ := f.Open()
+		data, _ := ioutil.ReadAll(r)
+		r.Close()
+		contents[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := contents[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+	if !strings.Contains(contents["xl/workbook.xml"], `name="Marks &amp; checks"`) {
+		t.Error("sheet name not escaped")
+	}
+	if !strings.Contains(contents["xl/worksheets/sheet1.xml"], `<c r="B2" s="2"><v>2.5</v></c>`) {
+		t.Error("highlighted number cell not written")
+	}
+	if !strings.Contains(contents["xl/worksheets/sheet1.xml"], `state="frozen"`) {
+		t.Error("header row not frozen")
+	}
+}
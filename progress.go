@@ -0,0 +1,68 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"flag"
+	"fmt"
+	"path/filepath"
+)
+
+func runProgress(args []string) error {
+
+	fs := flag.NewFlagSet("progress", flag.ExitOnError)
+
+	var inputDir string
+	inputDirFlag(fs, &inputDir)
+
+	var workers int
+	jobsFlag(fs, &workers)
+
+	var configJSON string
+	configFlag(fs, &configJSON)
+
+	var noCache bool
+	cacheFlag(fs, &noCache)
+
+	var partsCSV string
+	partsFlag(fs, &partsCSV)
+
+	var rawCSV string
+	fs.StringVar(&rawCSV, "raw", "", "path to an existing raw form values csv to use instead of reading the PDFs again")
+
+	fs.Parse(args)
+
+	report_time := reportTime()
+	outputDir := inputDir
+
+	var form_values []pdf.FormValues
+	var err error
+	if rawCSV != "" {
+		outputDir = filepath.Dir(rawCSV)
+		form_values, err = pdf.ReadFormValuesCSV(rawCSV)
+	} else {
+		form_values, err = extractForms(inputDir, inputDir, workers, configJSON, noCache, report_time)
+	}
+	if err != nil {
+		return err
+	}
+
+	parts, err := loadParts(outputDir, partsCSV)
+	if err != nil {
+		return err
+	}
+
+	return writeProgress(form_values, parts, outputDir, report_time)
+}
+
+// writeProgress prints each marker's progress, and saves it as a csv
+func writeProgress(form_values []pdf.FormValues, parts []*pdf.PaperStructure, outputDir string, report_time string) error {
+
+	report := pdf.MarkerProgressReport(form_values, parts)
+
+	fmt.Printf("\n%-10s %8s %8s %8s %8s %12s %12s\n", "Marker", "Scripts", "Done", "Partial", "Not yet", "Pages seen", "Parts to do")
+	for _, p := range report {
+		fmt.Printf("%-10s %8d %8d %8d %8d %5d / %-5d %12d\n", p.Marker, p.Scripts, p.FullyMarked, p.PartiallyMarked, p.Untouched, p.PagesSeen, p.Pages, p.PartsOutstanding)
+	}
+
+	return pdf.WriteMarkerProgressCSV(report, fmt.Sprintf("%s/06_marker_progress-%s.csv", outputDir, report_time))
+}
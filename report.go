@@ -0,0 +1,75 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"flag"
+	"fmt"
+)
+
+func runReport(args []string) error {
+
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+
+	var inputDir string
+	inputDirFlag(fs, &inputDir)
+
+	var workers int
+	jobsFlag(fs, &workers)
+
+	var configJSON string
+	configFlag(fs, &configJSON)
+
+	var noCache bool
+	cacheFlag(fs, &noCache)
+
+	var partsCSV string
+	partsFlag(fs, &partsCSV)
+
+	var rounding string
+	roundingFlag(fs, &rounding)
+
+	var histogramWidth float64
+	histogramFlag(fs, &histogramWidth)
+
+	var gradesJSON string
+	gradesFlag(fs, &gradesJSON)
+
+	var scalingJSON string
+	scalingFlag(fs, &scalingJSON)
+
+	fs.Parse(args)
+
+	opts := pdf.ValidationOptions{HistogramWidth: histogramWidth}
+	var err error
+	if opts.TotalRounding, err = pdf.ParseRounding(rounding); err != nil {
+		return err
+	}
+	if opts.Grades, err = loadGrades(gradesJSON); err != nil {
+		return err
+	}
+
+	if err := checkInputDir(inputDir); err != nil {
+		return err
+	}
+
+	parts, err := loadParts(inputDir, partsCSV)
+	if err != nil {
+		return err
+	}
+	if opts.Scaling, err = loadScaling(scalingJSON, parts); err != nil {
+		return err
+	}
+
+	report_time := reportTime()
+
+	// Look at all PDFs in inputDir (including subdirectories)
+	fmt.Println("Looking at input directory: ",inputDir)
+
+	// Read the raw form values, and save them as a csv
+	form_values, err := extractForms(inputDir, inputDir, workers, configJSON, noCache, report_time)
+	if err != nil {
+		return err
+	}
+
+	return summarise(form_values, parts, opts, inputDir, report_time)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func runServe(args []string) error {
+
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+
+	var inputDir string
+	inputDirFlag(fs, &inputDir)
+
+	var workers int
+	jobsFlag(fs, &workers)
+
+	var configJSON string
+	configFlag(fs, &configJSON)
+
+	var partsCSV string
+	partsFlag(fs, &partsCSV)
+
+	var rounding string
+	roundingFlag(fs, &rounding)
+
+	var histogramWidth float64
+	histogramFlag(fs, &histogramWidth)
+
+	var gradesJSON string
+	gradesFlag(fs, &gradesJSON)
+
+	var scalingJSON string
+	scalingFlag(fs, &scalingJSON)
+
+	var addr string
+	fs.StringVar(&addr, "addr", "localhost:8080", "address to serve the dashboard on")
+
+	var interval, settle time.Duration
+	fs.DurationVar(&interval, "interval", 5*time.Second, "how often to look for new or changed PDFs")
+	fs.DurationVar(&settle, "settle", 10*time.Second, "how long the PDFs must stay the same before they are read")
+
+	fs.Parse(args)
+
+	opts := pdf.ValidationOptions{HistogramWidth: histogramWidth}
+	var err error
+	if opts.TotalRounding, err = pdf.ParseRounding(rounding); err != nil {
+		return err
+	}
+	if opts.Grades, err = loadGrades(gradesJSON); err != nil {
+		return err
+	}
+
+	if err := checkInputDir(inputDir); err != nil {
+		return err
+	}
+	conv, err := loadConventions(configJSON)
+	if err != nil {
+		return err
+	}
+
+	parts, err := loadParts(inputDir, partsCSV)
+	if err != nil {
+		return err
+	}
+	if opts.Scaling, err = loadScaling(scalingJSON, parts); err != nil {
+		return err
+	}
+
+	// the dashboard only reads the scripts, so its cache is kept out of the markers' folder
+	cacheDir, err := ioutil.TempDir("", "gradex-serve")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(cacheDir)
+
+	dashboard := &pdf.Dashboard{}
+
+	// keep the dashboard up to date as the PDFs change
+	go func() {
+		watcher := &pdf.Watcher{Settle: settle}
+		for {
+			if watcher.Ready(pdf.ScanPDFs(inputDir), time.Now()) {
+				form_values, script_errors, changes := pdf.ReadFormsInDirectory(inputDir, "", pdf.ExtractOptions{Workers: workers, Conventions: conv, CachePath: filepath.Join(cacheDir, pdf.CacheFilename)})
+				printChanges(changes)
+				dashboard.Update(form_values, script_errors, parts, opts, pdf.ScriptPaths(inputDir, conv))
+				fmt.Println("Dashboard updated at", reportTime())
+			}
+			time.Sleep(interval)
+		}
+	}()
+
+	fmt.Printf("Serving the dashboard at http://%s/ (Ctrl-C to stop)\n", addr)
+	return http.ListenAndServe(addr, dashboard.Handler())
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func runStamp(args []string) error {
+
+	fs := flag.NewFlagSet("stamp", flag.ExitOnError)
+
+	var inputDir string
+	inputDirFlag(fs, &inputDir)
+
+	var workers int
+	jobsFlag(fs, &workers)
+
+	var configJSON string
+	configFlag(fs, &configJSON)
+
+	var noCache bool
+	cacheFlag(fs, &noCache)
+
+	var partsCSV string
+	partsFlag(fs, &partsCSV)
+
+	var rounding string
+	roundingFlag(fs, &rounding)
+
+	var gradesJSON string
+	gradesFlag(fs, &gradesJSON)
+
+	var scalingJSON string
+	scalingFlag(fs, &scalingJSON)
+
+	var rawCSV string
+	fs.StringVar(&rawCSV, "raw", "", "path to an existing raw form values csv to use instead of reading the PDFs again")
+
+	var outputDir string
+	fs.StringVar(&outputDir, "outputdir", "", "folder to save the summarised scripts in (default: next to the inputdir, with -summarised on the end of its name)")
+
+	fs.Parse(args)
+
+	var opts pdf.ValidationOptions
+	var err error
+	if opts.TotalRounding, err = pdf.ParseRounding(rounding); err != nil {
+		return err
+	}
+	if opts.Grades, err = loadGrades(gradesJSON); err != nil {
+		return err
+	}
+	conv, err := loadConventions(configJSON)
+	if err != nil {
+		return err
+	}
+
+	// keep the summarised copies out of the inputdir, so they are not read as scripts next time
+	if outputDir == "" {
+		abs, err := filepath.Abs(inputDir)
+		if err != nil {
+			return err
+		}
+		outputDir = abs + "-summarised"
+	}
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	report_time := reportTime()
+	partsDir := inputDir
+	var form_values []pdf.FormValues
+	if rawCSV != "" {
+		partsDir = filepath.Dir(rawCSV)
+		form_values, err = pdf.ReadFormValuesCSV(rawCSV)
+	} else {
+		form_values, err = extractForms(inputDir, inputDir, workers, configJSON, noCache, report_time)
+	}
+	if err != nil {
+		return err
+	}
+
+	parts, err := loadParts(partsDir, partsCSV)
+	if err != nil {
+		return err
+	}
+	if opts.Scaling, err = loadScaling(scalingJSON, parts); err != nil {
+		return err
+	}
+	if err := checkSingleCourse(form_values); err != nil {
+		return err
+	}
+	summary := pdf.SummariseMarking(form_values, parts, opts)
+
+	// stamp a cover page into each copy of each script, keeping the sub-folders so that each marker's copy is kept
+	paths := pdf.ScriptPaths(inputDir, conv)
+	stamped := 0
+	script_errors := []pdf.ScriptError{}
+	for _, script := range summary.Scripts {
+		for _, path := range paths[script.ExamNumber] {
+			rel, err := filepath.Rel(inputDir, path)
+			if err != nil {
+				return err
+			}
+			output_path := filepath.Join(outputDir, strings.TrimSuffix(rel, filepath.Ext(rel))+"-summarised.pdf")
+			if err := os.MkdirAll(filepath.Dir(output_path), os.ModePerm); err != nil {
+				return err
+			}
+			if err := pdf.StampCoverPage(path, summary, script, output_path); err != nil {
+				fmt.Println(" - ", path, err)
+				script_errors = append(script_errors, pdf.ScriptError{File: path, Stage: pdf.StageOutput, Reason: err.Error()})
+				continue
+			}
+			stamped++
+		}
+	}
+	fmt.Printf("Saved %d summarised scripts in %s\n", stamped, outputDir)
+
+	if len(script_errors) > 0 {
+		return writeScriptErrors(script_errors, outputDir, report_time)
+	}
+	return nil
+}
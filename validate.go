@@ -0,0 +1,115 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+)
+
+func runValidate(args []string) error {
+
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+
+	var rawCSV string
+	fs.StringVar(&rawCSV, "raw", "", "path to an existing raw form values csv (01_raw_form_values-*.csv)")
+
+	var partsCSV string
+	partsFlag(fs, &partsCSV)
+
+	var rounding string
+	roundingFlag(fs, &rounding)
+
+	var histogramWidth float64
+	histogramFlag(fs, &histogramWidth)
+
+	var gradesJSON string
+	gradesFlag(fs, &gradesJSON)
+
+	var scalingJSON string
+	scalingFlag(fs, &scalingJSON)
+
+	var outputDir string
+	fs.StringVar(&outputDir, "outputdir", "", "folder to write the marks summary to (default: the folder containing the raw csv)")
+
+	fs.Parse(args)
+
+	opts := pdf.ValidationOptions{HistogramWidth: histogramWidth}
+	var err error
+	if opts.TotalRounding, err = pdf.ParseRounding(rounding); err != nil {
+		return err
+	}
+	if opts.Grades, err = loadGrades(gradesJSON); err != nil {
+		return err
+	}
+
+	if rawCSV == "" {
+		return errors.New("specify the raw form values csv with -raw")
+	}
+	if outputDir == "" {
+		outputDir = filepath.Dir(rawCSV)
+	}
+
+	parts, err := loadParts(outputDir, partsCSV)
+	if err != nil {
+		return err
+	}
+	if opts.Scaling, err = loadScaling(scalingJSON, parts); err != nil {
+		return err
+	}
+
+	form_values, err := pdf.ReadFormValuesCSV(rawCSV)
+	if err != nil {
+		return err
+	}
+
+	return summarise(form_values, parts, opts, outputDir, reportTime())
+}
+
+// summarise writes the marks summary for a set of form values
+func summarise(form_values []pdf.FormValues, parts []*pdf.PaperStructure, opts pdf.ValidationOptions, outputDir string, report_time string) error {
+
+	if err := checkSingleCourse(form_values); err != nil {
+		return err
+	}
+
+	// Now summarise the marks and perform validation checks
+	summary := pdf.SummariseMarking(form_values, parts, opts)
+
+	csv_path := fmt.Sprintf("%s/00_marks_summary-%s.csv", outputDir, report_time)
+	if err := pdf.WriteSummaryCSV(summary, csv_path); err != nil {
+		return err
+	}
+	json_path := fmt.Sprintf("%s/00_marks_summary-%s.json", outputDir, report_time)
+	if err := pdf.WriteSummaryJSON(summary, json_path); err != nil {
+		return err
+	}
+	xlsx_path := fmt.Sprintf("%s/00_marks_summary-%s.xlsx", outputDir, report_time)
+	if err := pdf.WriteSummaryXLSX(summary, form_values, xlsx_path); err != nil {
+		return err
+	}
+
+	// Record the scaling, and who approved it
+	if summary.Scaling != nil {
+		if err := pdf.WriteScalingAudit(summary, fmt.Sprintf("%s/09_scaling_audit-%s.json", outputDir, report_time)); err != nil {
+			return err
+		}
+	}
+
+	// Item analysis of each part, for reviewing the paper
+	if err := pdf.WriteItemAnalysisCSV(pdf.AnalyseItems(summary), fmt.Sprintf("%s/08_item_analysis-%s.csv", outputDir, report_time)); err != nil {
+		return err
+	}
+
+	// Keep a record of what moderation changed
+	audit := pdf.AuditModeration(form_values, parts)
+	if len(audit.Parts) == 0 {
+		return nil
+	}
+	fmt.Printf("%d parts have been moderated\n", len(audit.Parts))
+	if err := pdf.WriteModerationCSV(audit, fmt.Sprintf("%s/05_moderation-%s.csv", outputDir, report_time)); err != nil {
+		return err
+	}
+	return pdf.WriteModerationJSON(audit, fmt.Sprintf("%s/05_moderation-%s.json", outputDir, report_time))
+}
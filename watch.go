@@ -0,0 +1,108 @@
+package main
+
+import (
+	pdf "github.com/georgekinnear/gradex-extract/pdfextract"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func runWatch(args []string) error {
+
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+
+	var inputDir string
+	inputDirFlag(fs, &inputDir)
+
+	var workers int
+	jobsFlag(fs, &workers)
+
+	var configJSON string
+	configFlag(fs, &configJSON)
+
+	var partsCSV string
+	partsFlag(fs, &partsCSV)
+
+	var rounding string
+	roundingFlag(fs, &rounding)
+
+	var histogramWidth float64
+	histogramFlag(fs, &histogramWidth)
+
+	var gradesJSON string
+	gradesFlag(fs, &gradesJSON)
+
+	var scalingJSON string
+	scalingFlag(fs, &scalingJSON)
+
+	var interval, settle time.Duration
+	fs.DurationVar(&interval, "interval", 5*time.Second, "how often to look for new or changed PDFs")
+	fs.DurationVar(&settle, "settle", 10*time.Second, "how long the PDFs must stay the same before they are read, so that files still being copied in are left alone")
+
+	var outputDir string
+	fs.StringVar(&outputDir, "outputdir", "", "folder to keep the latest reports in (default: next to the inputdir, with -reports on the end of its name)")
+
+	fs.Parse(args)
+
+	opts := pdf.ValidationOptions{HistogramWidth: histogramWidth}
+	var err error
+	if opts.TotalRounding, err = pdf.ParseRounding(rounding); err != nil {
+		return err
+	}
+	if opts.Grades, err = loadGrades(gradesJSON); err != nil {
+		return err
+	}
+
+	if err := checkInputDir(inputDir); err != nil {
+		return err
+	}
+	if _, err := loadConventions(configJSON); err != nil {
+		return err
+	}
+
+	parts, err := loadParts(inputDir, partsCSV)
+	if err != nil {
+		return err
+	}
+	if opts.Scaling, err = loadScaling(scalingJSON, parts); err != nil {
+		return err
+	}
+
+	// keep the reports out of the markers' folder, and overwrite them each time rather than piling up a new set
+	if outputDir == "" {
+		abs, err := filepath.Abs(inputDir)
+		if err != nil {
+			return err
+		}
+		outputDir = abs + "-reports"
+	}
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return err
+	}
+	const report_time = "latest"
+
+	fmt.Printf("Watching %s for new or changed PDFs (Ctrl-C to stop), with the reports in %s\n", inputDir, outputDir)
+
+	watcher := &pdf.Watcher{Settle: settle}
+	for {
+		if watcher.Ready(pdf.ScanPDFs(inputDir), time.Now()) {
+			fmt.Println("\nUpdating reports at", reportTime())
+
+			// only the new and changed scripts are read, thanks to the cache
+			// carry on watching if the reports can't be written this time, e.g. while there are no scripts yet
+			form_values, err := extractForms(inputDir, outputDir, workers, configJSON, false, report_time)
+			if err != nil {
+				fmt.Println("Error:", err)
+			}
+			if err := summarise(form_values, parts, opts, outputDir, report_time); err != nil {
+				fmt.Println("Error:", err)
+			}
+			if err := writeProgress(form_values, parts, outputDir, report_time); err != nil {
+				fmt.Println("Error:", err)
+			}
+		}
+		time.Sleep(interval)
+	}
+}